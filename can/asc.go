@@ -0,0 +1,253 @@
+package can
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ascDateLayouts are the layouts Vector tools use for the date header.
+var ascDateLayouts = []string{
+	"Mon Jan _2 03:04:05.000 pm 2006",
+	"Mon Jan _2 03:04:05.000 PM 2006",
+	"Mon Jan _2 03:04:05 pm 2006",
+	"Mon Jan _2 03:04:05 PM 2006",
+	"Mon Jan _2 15:04:05.000 2006",
+	"Mon Jan _2 15:04:05 2006",
+}
+
+var (
+	ascFrameRegexp = regexp.MustCompile(`^(\d+\.\d+)\s+\d+\s+([0-9A-Fa-f]+)(x?)\s+(?:Rx|Tx)\s+([dr])(?:\s+([0-9A-Fa-f])\b)?((?:\s+[0-9A-Fa-f]+)*)`)
+	// ascFDFrameRegexp matches CAN FD frames which have an optional symbolic
+	// name after the ID followed by the bit rate switch, error state
+	// indicator, DLC and data length.
+	ascFDFrameRegexp    = regexp.MustCompile(`^(\d+\.\d+)\s+CANFD\s+\d+\s+(?:Rx|Tx)\s+([0-9A-Fa-f]+)(x?)\s+(?:\S+\s+)??[01]\s+[01]\s+[0-9A-Fa-f]+\s+(\d+)((?:\s+[0-9A-Fa-f]+)*)`)
+	ascErrorFrameRegexp = regexp.MustCompile(`^(\d+\.\d+)\s+\d+\s+ErrorFrame`)
+)
+
+// ASCReader reads Vector ASCII logs. Data, remote, CAN FD and error frames are
+// returned, other events are skipped. The date header is read as UTC to match
+// ASCWriter.
+type ASCReader struct {
+	s        *bufio.Scanner
+	line     int
+	start    time.Time
+	base     int
+	relative bool
+	last     time.Duration
+}
+
+func NewASCReader(r io.Reader) *ASCReader {
+	return &ASCReader{s: bufio.NewScanner(r), base: 16}
+}
+
+func (r *ASCReader) Read() (Record, error) {
+	for r.s.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.s.Text())
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "date":
+			date := strings.Join(fields[1:], " ")
+			for _, layout := range ascDateLayouts {
+				if t, err := time.ParseInLocation(layout, date, time.UTC); err == nil {
+					r.start = t
+					break
+				}
+			}
+			continue
+		case "base":
+			if len(fields) >= 2 && fields[1] == "dec" {
+				r.base = 10
+			}
+			r.relative = strings.Contains(line, "timestamps relative")
+			continue
+		}
+
+		var record Record
+		var err error
+		if matches := ascFrameRegexp.FindStringSubmatch(line); matches != nil {
+			record, err = r.parseFrame(matches)
+		} else if matches := ascFDFrameRegexp.FindStringSubmatch(line); matches != nil {
+			record, err = r.parseFDFrame(matches)
+		} else if matches := ascErrorFrameRegexp.FindStringSubmatch(line); matches != nil {
+			record.Frame.Error = true
+			record.Time, err = r.parseTime(matches[1])
+		} else {
+			continue
+		}
+		if err != nil {
+			return Record{}, errors.Wrapf(err, "line %d", r.line)
+		}
+		return record, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+// parseTime converts a log timestamp in seconds into a time.
+func (r *ASCReader) parseTime(s string) (time.Time, error) {
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	offset := time.Duration(math.Round(secs * float64(time.Second)))
+	if r.relative {
+		offset += r.last
+	}
+	r.last = offset
+	return r.start.Add(offset), nil
+}
+
+// parseID parses the ID and extended marker of a frame.
+func (r *ASCReader) parseID(id, x string, frame *Frame) error {
+	parsed, err := strconv.ParseUint(id, r.base, 29)
+	if err != nil {
+		return err
+	}
+	frame.ID = int(parsed)
+	frame.Extended = x == "x"
+	return nil
+}
+
+// parseData parses n data bytes.
+func (r *ASCReader) parseData(n int, fields []string, frame *Frame) error {
+	if n > len(fields) {
+		return errors.Errorf("data length %d but only %d data bytes", n, len(fields))
+	}
+	frame.Data = make([]byte, n)
+	for i, b := range fields[:n] {
+		parsed, err := strconv.ParseUint(b, r.base, 8)
+		if err != nil {
+			return err
+		}
+		frame.Data[i] = byte(parsed)
+	}
+	return nil
+}
+
+func (r *ASCReader) parseFrame(matches []string) (Record, error) {
+	t, err := r.parseTime(matches[1])
+	if err != nil {
+		return Record{}, err
+	}
+	var frame Frame
+	if err := r.parseID(matches[2], matches[3], &frame); err != nil {
+		return Record{}, err
+	}
+	var dlc uint64
+	if matches[5] != "" {
+		if dlc, err = strconv.ParseUint(matches[5], 16, 8); err != nil {
+			return Record{}, err
+		}
+	}
+	n := DLCToLen(int(dlc), false)
+	if matches[4] == "r" {
+		frame.Remote = true
+		frame.Data = make([]byte, n)
+	} else if err := r.parseData(n, strings.Fields(matches[6]), &frame); err != nil {
+		return Record{}, err
+	}
+	return Record{Frame: frame, Time: t}, nil
+}
+
+func (r *ASCReader) parseFDFrame(matches []string) (Record, error) {
+	t, err := r.parseTime(matches[1])
+	if err != nil {
+		return Record{}, err
+	}
+	frame := Frame{FD: true}
+	if err := r.parseID(matches[2], matches[3], &frame); err != nil {
+		return Record{}, err
+	}
+	n, err := strconv.Atoi(matches[4])
+	if err != nil {
+		return Record{}, err
+	}
+	if !ValidDataLen(n, true) {
+		return Record{}, errors.Errorf("invalid CAN FD data length %d", n)
+	}
+	if err := r.parseData(n, strings.Fields(matches[5]), &frame); err != nil {
+		return Record{}, err
+	}
+	return Record{Frame: frame, Time: t}, nil
+}
+
+// ASCWriter writes Vector ASCII logs with absolute timestamps relative to the
+// first record. The date header is written in UTC and only has millisecond
+// precision so the timestamps are relative to the first record's time
+// truncated to the millisecond.
+type ASCWriter struct {
+	w       *bufio.Writer
+	start   time.Time
+	started bool
+}
+
+func NewASCWriter(w io.Writer) *ASCWriter {
+	return &ASCWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *ASCWriter) Write(record Record) error {
+	if !w.started {
+		w.started = true
+		w.start = record.Time.UTC().Truncate(time.Millisecond)
+		date := w.start.Format(ascDateLayouts[0])
+		if _, err := fmt.Fprintf(
+			w.w,
+			"date %s\nbase hex  timestamps absolute\ninternal events logged\nBegin Triggerblock %s\n   0.000000 Start of measurement\n",
+			date, date,
+		); err != nil {
+			return err
+		}
+	}
+
+	frame := record.Frame
+	offset := record.Time.Sub(w.start).Seconds()
+	if frame.Error {
+		_, err := fmt.Fprintf(w.w, "%11.6f 1  ErrorFrame\n", offset)
+		return err
+	}
+	id := fmt.Sprintf("%X", frame.ID)
+	if frame.Extended {
+		id += "x"
+	}
+	var data strings.Builder
+	for _, b := range frame.Data {
+		fmt.Fprintf(&data, " %02X", b)
+	}
+	var err error
+	switch {
+	case frame.FD:
+		_, err = fmt.Fprintf(
+			w.w, "%11.6f CANFD   1 Rx   %-15s 0 0 %X %2d%s\n",
+			offset, id, frame.DLC(), len(frame.Data), data.String(),
+		)
+	case frame.Remote:
+		_, err = fmt.Fprintf(w.w, "%11.6f 1  %-15s Rx   r %d\n", offset, id, frame.DLC())
+	default:
+		_, err = fmt.Fprintf(w.w, "%11.6f 1  %-15s Rx   d %d%s\n", offset, id, frame.DLC(), data.String())
+	}
+	return err
+}
+
+func (w *ASCWriter) Close() error {
+	if w.started {
+		if _, err := w.w.WriteString("End TriggerBlock\n"); err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
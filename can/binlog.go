@@ -0,0 +1,610 @@
+package can
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The binary log format is a magic header followed by length-prefixed blocks
+// of records. Each block is:
+//
+//	flags       byte    binaryFlagCompressed if the payload is deflated
+//	count       uvarint number of records
+//	base        varint  unix nanoseconds of the first record
+//	length      uvarint payload length in bytes
+//	payload     records
+//
+// and each record within the payload is:
+//
+//	delta       uvarint nanoseconds since the previous record
+//	id          uvarint frame ID
+//	frame flags byte    binaryFrameExtended etc, since version 2
+//	bus         byte length and name, only if binaryFrameBus is set
+//	length      byte    payload length
+//	data        length bytes, omitted for remote frames
+//
+// Files may be appended to by writing another header followed by more blocks.
+// Version 1 files have no frame flags.
+//
+// Since version 3 closing a writer ends its blocks with an index block, which
+// readers skip. Its payload lists the writer's blocks so they can be found
+// without reading every block header:
+//
+//	back        uvarint bytes from the index block back to the block
+//	base        varint  unix nanoseconds of the block's first record
+//	count       uvarint number of records in the block
+//
+// repeated for each block, followed by
+//
+//	start       uvarint bytes from the index block back to the writer's header
+//	length      uint64  little endian length of the whole index block
+//	magic       "RIDX"
+//
+// so the index can be found from the end of the file. Files that were
+// appended to chain through start to the index before them. Files without a
+// valid index, such as ones that weren't closed, are scanned instead.
+const (
+	binaryMagic   = "RCAN"
+	binaryVersion = 3
+
+	binaryFlagCompressed = 1 << 0
+	binaryFlagIndex      = 1 << 1
+
+	binaryIndexMagic = "RIDX"
+	// binaryTrailerLen is the length of the index length and magic.
+	binaryTrailerLen = 8 + len(binaryIndexMagic)
+
+	binaryFrameExtended = 1 << 0
+	binaryFrameRemote   = 1 << 1
+	binaryFrameError    = 1 << 2
+	binaryFrameFD       = 1 << 3
+	binaryFrameBus      = 1 << 4
+
+	// DefaultBinaryBlockSize is the number of records per block.
+	DefaultBinaryBlockSize = 1024
+)
+
+// BinaryWriter writes the compact binary log format. Records are buffered and
+// written a block at a time.
+type BinaryWriter struct {
+	w           io.Writer
+	wroteHeader bool
+
+	// Compress deflates each block.
+	Compress bool
+	// BlockSize is the number of records buffered before a block is written.
+	BlockSize int
+
+	buf   bytes.Buffer
+	count int
+	base  time.Time
+	last  time.Time
+
+	// offset is the number of bytes written, blocks are the blocks written
+	// so far for the index.
+	offset int64
+	blocks []binaryIndexEntry
+}
+
+// binaryIndexEntry is a block written by a BinaryWriter. offset is relative
+// to the writer's header.
+type binaryIndexEntry struct {
+	offset int64
+	base   time.Time
+	count  int
+}
+
+func NewBinaryWriter(w io.Writer) *BinaryWriter {
+	return &BinaryWriter{
+		w:         w,
+		Compress:  true,
+		BlockSize: DefaultBinaryBlockSize,
+	}
+}
+
+func (w *BinaryWriter) Write(record Record) error {
+	if w.count == 0 {
+		w.base = record.Time
+		w.last = record.Time
+	}
+	delta := record.Time.Sub(w.last)
+	if delta < 0 {
+		delta = 0
+	}
+	w.last = w.last.Add(delta)
+
+	var scratch [binary.MaxVarintLen64]byte
+	w.buf.Write(scratch[:binary.PutUvarint(scratch[:], uint64(delta))])
+	w.buf.Write(scratch[:binary.PutUvarint(scratch[:], uint64(record.Frame.ID))])
+	flags := binaryFrameFlags(record.Frame)
+	if record.Bus != "" {
+		if len(record.Bus) > 0xFF {
+			return errors.Errorf("bus name too long: %q", record.Bus)
+		}
+		flags |= binaryFrameBus
+	}
+	w.buf.WriteByte(flags)
+	if record.Bus != "" {
+		w.buf.WriteByte(byte(len(record.Bus)))
+		w.buf.WriteString(record.Bus)
+	}
+	w.buf.WriteByte(byte(len(record.Frame.Data)))
+	if !record.Frame.Remote {
+		w.buf.Write(record.Frame.Data)
+	}
+	w.count++
+
+	if w.count >= w.BlockSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes the buffered records as a block.
+func (w *BinaryWriter) Flush() error {
+	if !w.wroteHeader {
+		header := append([]byte(binaryMagic), binaryVersion)
+		if _, err := w.w.Write(header); err != nil {
+			return err
+		}
+		w.wroteHeader = true
+		w.offset += int64(len(header))
+	}
+	if w.count == 0 {
+		return nil
+	}
+
+	payload := w.buf.Bytes()
+	var flags byte
+	if w.Compress {
+		var compressed bytes.Buffer
+		fw, err := flate.NewWriter(&compressed, flate.DefaultCompression)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(payload); err != nil {
+			return err
+		}
+		if err := fw.Close(); err != nil {
+			return err
+		}
+		payload = compressed.Bytes()
+		flags |= binaryFlagCompressed
+	}
+
+	var header bytes.Buffer
+	var scratch [binary.MaxVarintLen64]byte
+	header.WriteByte(flags)
+	header.Write(scratch[:binary.PutUvarint(scratch[:], uint64(w.count))])
+	header.Write(scratch[:binary.PutVarint(scratch[:], w.base.UnixNano())])
+	header.Write(scratch[:binary.PutUvarint(scratch[:], uint64(len(payload)))])
+	// Write the block in a single call so a crash leaves at most one partial
+	// block at the end of the file.
+	block := append(header.Bytes(), payload...)
+	if _, err := w.w.Write(block); err != nil {
+		return err
+	}
+	w.blocks = append(w.blocks, binaryIndexEntry{offset: w.offset, base: w.base, count: w.count})
+	w.offset += int64(len(block))
+
+	w.buf.Reset()
+	w.count = 0
+	return nil
+}
+
+// Close flushes the buffered records and writes the index.
+func (w *BinaryWriter) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	var payload bytes.Buffer
+	var scratch [binary.MaxVarintLen64]byte
+	for _, b := range w.blocks {
+		payload.Write(scratch[:binary.PutUvarint(scratch[:], uint64(w.offset-b.offset))])
+		payload.Write(scratch[:binary.PutVarint(scratch[:], b.base.UnixNano())])
+		payload.Write(scratch[:binary.PutUvarint(scratch[:], uint64(b.count))])
+	}
+	payload.Write(scratch[:binary.PutUvarint(scratch[:], uint64(w.offset))])
+
+	h := blockHeader{
+		flags:  binaryFlagIndex,
+		count:  len(w.blocks),
+		base:   time.Unix(0, 0),
+		length: payload.Len() + binaryTrailerLen,
+	}
+	var block bytes.Buffer
+	block.WriteByte(h.flags)
+	block.Write(scratch[:binary.PutUvarint(scratch[:], uint64(h.count))])
+	block.Write(scratch[:binary.PutVarint(scratch[:], h.base.UnixNano())])
+	block.Write(scratch[:binary.PutUvarint(scratch[:], uint64(h.length))])
+	block.Write(payload.Bytes())
+	var trailer [8]byte
+	binary.LittleEndian.PutUint64(trailer[:], uint64(blockHeaderLen(h)+h.length))
+	block.Write(trailer[:])
+	block.WriteString(binaryIndexMagic)
+	if _, err := w.w.Write(block.Bytes()); err != nil {
+		return err
+	}
+	w.offset += int64(block.Len())
+	return nil
+}
+
+// BinaryBlock describes a block in a binary log.
+type BinaryBlock struct {
+	// Offset is the position of the block header in the file.
+	Offset int64
+	// Time is the time of the first record in the block.
+	Time  time.Time
+	Count int
+
+	version byte
+}
+
+type blockHeader struct {
+	// version is the version of the file header in front of the block.
+	version byte
+	flags   byte
+	count   int
+	base    time.Time
+	length  int
+}
+
+// BinaryReader reads the compact binary log format.
+type BinaryReader struct {
+	r *bufio.Reader
+
+	version byte
+	payload *bufio.Reader
+	left    int
+	last    time.Time
+}
+
+func NewBinaryReader(r io.Reader) *BinaryReader {
+	return &BinaryReader{r: bufio.NewReader(r)}
+}
+
+// readBlockHeader reads the next block header, skipping any file headers in
+// front of it. version is the version of the previous block. It also returns
+// the number of bytes read.
+func readBlockHeader(r *bufio.Reader, version byte) (blockHeader, int, error) {
+	h := blockHeader{version: version}
+	n := 0
+	for {
+		peek, err := r.Peek(1)
+		if err != nil {
+			return h, n, err
+		}
+		if peek[0] != binaryMagic[0] {
+			break
+		}
+		magic := make([]byte, len(binaryMagic)+1)
+		if _, err := io.ReadFull(r, magic); err != nil {
+			return h, n, noEOF(err)
+		}
+		n += len(magic)
+		if string(magic[:len(binaryMagic)]) != binaryMagic {
+			return h, n, errors.Errorf("invalid binary log header %q", magic)
+		}
+		h.version = magic[len(binaryMagic)]
+		if h.version < 1 || h.version > binaryVersion {
+			return h, n, errors.Errorf("unsupported binary log version %d", h.version)
+		}
+	}
+
+	cr := &countingByteReader{r: r}
+	var err error
+	if h.flags, err = cr.ReadByte(); err != nil {
+		return h, n, err
+	}
+	count, err := binary.ReadUvarint(cr)
+	if err != nil {
+		return h, n + cr.n, noEOF(err)
+	}
+	base, err := binary.ReadVarint(cr)
+	if err != nil {
+		return h, n + cr.n, noEOF(err)
+	}
+	length, err := binary.ReadUvarint(cr)
+	if err != nil {
+		return h, n + cr.n, noEOF(err)
+	}
+	h.count = int(count)
+	h.base = time.Unix(0, base)
+	h.length = int(length)
+	return h, n + cr.n, nil
+}
+
+func (r *BinaryReader) Read() (Record, error) {
+	for r.left == 0 {
+		h, _, err := readBlockHeader(r.r, r.version)
+		if err != nil {
+			return Record{}, err
+		}
+		if h.version == 0 {
+			return Record{}, errors.New("binary log block without a header")
+		}
+		r.version = h.version
+		if h.flags&binaryFlagIndex != 0 {
+			if _, err := io.CopyN(ioutil.Discard, r.r, int64(h.length)); err != nil {
+				return Record{}, noEOF(err)
+			}
+			continue
+		}
+		payload := make([]byte, h.length)
+		if _, err := io.ReadFull(r.r, payload); err != nil {
+			return Record{}, noEOF(err)
+		}
+		if h.flags&binaryFlagCompressed != 0 {
+			payload, err = ioutil.ReadAll(flate.NewReader(bytes.NewReader(payload)))
+			if err != nil {
+				return Record{}, errors.Wrap(err, "decompressing block")
+			}
+		}
+		r.payload = bufio.NewReader(bytes.NewReader(payload))
+		r.left = h.count
+		r.last = h.base
+	}
+
+	delta, err := binary.ReadUvarint(r.payload)
+	if err != nil {
+		return Record{}, noEOF(err)
+	}
+	id, err := binary.ReadUvarint(r.payload)
+	if err != nil {
+		return Record{}, noEOF(err)
+	}
+	var flags byte
+	if r.version >= 2 {
+		if flags, err = r.payload.ReadByte(); err != nil {
+			return Record{}, noEOF(err)
+		}
+	} else if id > MaxStandardID {
+		flags = binaryFrameExtended
+	}
+	var bus string
+	if flags&binaryFrameBus != 0 {
+		n, err := r.payload.ReadByte()
+		if err != nil {
+			return Record{}, noEOF(err)
+		}
+		name := make([]byte, n)
+		if _, err := io.ReadFull(r.payload, name); err != nil {
+			return Record{}, noEOF(err)
+		}
+		bus = string(name)
+	}
+	length, err := r.payload.ReadByte()
+	if err != nil {
+		return Record{}, noEOF(err)
+	}
+	if length > MaxFDDataLen {
+		return Record{}, errors.Errorf("invalid frame length %d", length)
+	}
+	frame := Frame{
+		ID:       int(id),
+		Extended: flags&binaryFrameExtended != 0,
+		Remote:   flags&binaryFrameRemote != 0,
+		Error:    flags&binaryFrameError != 0,
+		FD:       flags&binaryFrameFD != 0,
+		Data:     make([]byte, length),
+	}
+	if !frame.Remote {
+		if _, err := io.ReadFull(r.payload, frame.Data); err != nil {
+			return Record{}, noEOF(err)
+		}
+	}
+	r.left--
+	r.last = r.last.Add(time.Duration(delta))
+	return Record{Frame: frame, Time: r.last, Bus: bus}, nil
+}
+
+// ReadBinaryIndex lists the blocks in a binary log. It reads the index at the
+// end of the file, falling back to reading each block header if there isn't a
+// valid one.
+func ReadBinaryIndex(rs io.ReadSeeker) ([]BinaryBlock, error) {
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	return readBinaryIndex(rs, size)
+}
+
+// readBinaryIndex lists the blocks in the first end bytes of the file.
+func readBinaryIndex(rs io.ReadSeeker, end int64) ([]BinaryBlock, error) {
+	blocks, start, ok, err := readIndexBlock(rs, end)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return scanBinaryIndex(rs, end)
+	}
+	if start == 0 {
+		return blocks, nil
+	}
+	// The file was appended to, list the blocks in front of this writer's.
+	prev, err := readBinaryIndex(rs, start)
+	if err != nil {
+		return nil, err
+	}
+	return append(prev, blocks...), nil
+}
+
+// readIndexBlock reads the index block ending at end. It also returns the
+// offset of the header of the writer that wrote it. ok is false if there isn't
+// a valid index block.
+func readIndexBlock(rs io.ReadSeeker, end int64) (blocks []BinaryBlock, start int64, ok bool, err error) {
+	if end < int64(binaryTrailerLen) {
+		return nil, 0, false, nil
+	}
+	if _, err := rs.Seek(end-int64(binaryTrailerLen), io.SeekStart); err != nil {
+		return nil, 0, false, err
+	}
+	trailer := make([]byte, binaryTrailerLen)
+	if _, err := io.ReadFull(rs, trailer); err != nil {
+		return nil, 0, false, err
+	}
+	if string(trailer[8:]) != binaryIndexMagic {
+		return nil, 0, false, nil
+	}
+	length := binary.LittleEndian.Uint64(trailer)
+	if length > uint64(end) || length < uint64(binaryTrailerLen) {
+		return nil, 0, false, nil
+	}
+	indexStart := end - int64(length)
+	if _, err := rs.Seek(indexStart, io.SeekStart); err != nil {
+		return nil, 0, false, err
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(rs, block); err != nil {
+		return nil, 0, false, err
+	}
+
+	r := bufio.NewReader(bytes.NewReader(block[:len(block)-binaryTrailerLen]))
+	h, n, err := readBlockHeader(r, binaryVersion)
+	if err != nil || h.flags&binaryFlagIndex == 0 || uint64(n+h.length) != length {
+		return nil, 0, false, nil
+	}
+	for i := 0; i < h.count; i++ {
+		back, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, 0, false, nil
+		}
+		base, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, 0, false, nil
+		}
+		count, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, 0, false, nil
+		}
+		if back > uint64(indexStart) {
+			return nil, 0, false, nil
+		}
+		blocks = append(blocks, BinaryBlock{
+			Offset: indexStart - int64(back),
+			Time:   time.Unix(0, base),
+			Count:  int(count),
+
+			version: binaryVersion,
+		})
+	}
+	back, err := binary.ReadUvarint(r)
+	if err != nil || back > uint64(indexStart) {
+		return nil, 0, false, nil
+	}
+	return blocks, indexStart - int64(back), true, nil
+}
+
+// scanBinaryIndex lists the blocks in the first end bytes of the file by
+// reading each block header and seeking past the payload.
+func scanBinaryIndex(rs io.ReadSeeker, end int64) ([]BinaryBlock, error) {
+	var blocks []BinaryBlock
+	var offset int64
+	var version byte
+	for offset < end {
+		if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		// Block headers are small so avoid reading ahead into the payload.
+		h, n, err := readBlockHeader(bufio.NewReaderSize(rs, 16), version)
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return nil, err
+		}
+		version = h.version
+		if h.flags&binaryFlagIndex == 0 {
+			blocks = append(blocks, BinaryBlock{
+				Offset: offset + int64(n) - int64(blockHeaderLen(h)),
+				Time:   h.base,
+				Count:  h.count,
+
+				version: h.version,
+			})
+		}
+		offset += int64(n + h.length)
+	}
+	if offset > end {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return blocks, nil
+}
+
+// SeekBinary returns a reader positioned at the start of the last block
+// beginning at or before t.
+func SeekBinary(rs io.ReadSeeker, t time.Time) (*BinaryReader, error) {
+	blocks, err := ReadBinaryIndex(rs)
+	if err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(blocks), func(i int) bool {
+		return blocks[i].Time.After(t)
+	})
+	var offset int64
+	var version byte
+	if i > 0 {
+		offset = blocks[i-1].Offset
+		version = blocks[i-1].version
+	}
+	if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	reader := NewBinaryReader(rs)
+	reader.version = version
+	return reader, nil
+}
+
+func binaryFrameFlags(frame Frame) byte {
+	var flags byte
+	if frame.Extended {
+		flags |= binaryFrameExtended
+	}
+	if frame.Remote {
+		flags |= binaryFrameRemote
+	}
+	if frame.Error {
+		flags |= binaryFrameError
+	}
+	if frame.FD {
+		flags |= binaryFrameFD
+	}
+	return flags
+}
+
+func blockHeaderLen(h blockHeader) int {
+	var scratch [binary.MaxVarintLen64]byte
+	return 1 +
+		binary.PutUvarint(scratch[:], uint64(h.count)) +
+		binary.PutVarint(scratch[:], h.base.UnixNano()) +
+		binary.PutUvarint(scratch[:], uint64(h.length))
+}
+
+type countingByteReader struct {
+	r io.ByteReader
+	n int
+}
+
+func (r *countingByteReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.n++
+	}
+	return b, err
+}
+
+// noEOF converts io.EOF in the middle of a block into io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,343 @@
+package can
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var binaryStart = time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// binaryRecords returns n records a second apart with a mix of frame types.
+func binaryRecords(n int) []Record {
+	var records []Record
+	for i := 0; i < n; i++ {
+		r := Record{
+			Frame: Frame{ID: 0x100 + i, Data: []byte{byte(i), 2, 3}},
+			Time:  binaryStart.Add(time.Duration(i) * time.Second),
+		}
+		switch i % 4 {
+		case 1:
+			r.Frame = Frame{ID: 0x12345678, Extended: true, Remote: true, Data: make([]byte, 4)}
+		case 2:
+			r.Frame = Frame{ID: 0x3D2, FD: true, Data: bytes.Repeat([]byte{byte(i)}, 64)}
+			r.Bus = "chassis"
+		case 3:
+			r.Frame = Frame{ID: 0x4, Error: true, Data: make([]byte, 8)}
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func writeBinary(t *testing.T, w io.Writer, compress bool, blockSize int, records []Record) {
+	writer := NewBinaryWriter(w)
+	writer.Compress = compress
+	writer.BlockSize = blockSize
+	for _, r := range records {
+		if err := writer.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readBinary reads records until an error, returning io.EOF as nil.
+func readBinary(r *BinaryReader) ([]Record, error) {
+	var records []Record
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
+
+func checkRecords(t *testing.T, got, want []Record) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("read %d records; expected %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) || got[i].Bus != want[i].Bus || !reflect.DeepEqual(got[i].Frame, want[i].Frame) {
+			t.Errorf("%d: read %+v; expected %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	records := binaryRecords(10)
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		writeBinary(t, &buf, compress, 3, records)
+		got, err := readBinary(NewBinaryReader(&buf))
+		if err != nil {
+			t.Fatalf("compress %t: %v", compress, err)
+		}
+		checkRecords(t, got, records)
+	}
+}
+
+func TestBinaryAppend(t *testing.T) {
+	records := binaryRecords(8)
+	var buf bytes.Buffer
+	writeBinary(t, &buf, true, 3, records[:5])
+	writeBinary(t, &buf, false, 3, records[5:])
+	if n := bytes.Count(buf.Bytes(), []byte(binaryMagic)); n != 2 {
+		t.Fatalf("found %d headers; expected 2", n)
+	}
+
+	got, err := readBinary(NewBinaryReader(bytes.NewReader(buf.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, got, records)
+
+	blocks, err := ReadBinaryIndex(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("ReadBinaryIndex found %d blocks; expected 3", len(blocks))
+	}
+
+	// The block after the second header.
+	r, err := SeekBinary(bytes.NewReader(buf.Bytes()), records[5].Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = readBinary(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, got, records[5:])
+}
+
+// binaryV1 encodes records as a version 1 file in a single uncompressed
+// block.
+func binaryV1(records []Record) []byte {
+	var payload bytes.Buffer
+	var scratch [binary.MaxVarintLen64]byte
+	last := records[0].Time
+	for _, r := range records {
+		payload.Write(scratch[:binary.PutUvarint(scratch[:], uint64(r.Time.Sub(last)))])
+		last = r.Time
+		payload.Write(scratch[:binary.PutUvarint(scratch[:], uint64(r.Frame.ID))])
+		payload.WriteByte(byte(len(r.Frame.Data)))
+		payload.Write(r.Frame.Data)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(binaryMagic)
+	buf.WriteByte(1)
+	buf.WriteByte(0)
+	buf.Write(scratch[:binary.PutUvarint(scratch[:], uint64(len(records)))])
+	buf.Write(scratch[:binary.PutVarint(scratch[:], records[0].Time.UnixNano())])
+	buf.Write(scratch[:binary.PutUvarint(scratch[:], uint64(payload.Len()))])
+	buf.Write(payload.Bytes())
+	return buf.Bytes()
+}
+
+func TestBinaryVersion1(t *testing.T) {
+	records := []Record{
+		{Frame: Frame{ID: 0x118, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}}, Time: binaryStart},
+		// Version 1 has no flags so large IDs are extended.
+		{Frame: Frame{ID: 0x12345678, Extended: true, Data: []byte{9}}, Time: binaryStart.Add(time.Millisecond)},
+	}
+	file := binaryV1(records)
+	got, err := readBinary(NewBinaryReader(bytes.NewReader(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, got, records)
+
+	// Appending to a version 1 file writes a version 2 header.
+	more := binaryRecords(3)
+	buf := bytes.NewBuffer(file)
+	writeBinary(t, buf, true, 10, more)
+	got, err = readBinary(NewBinaryReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, got, append(records, more...))
+}
+
+// writeBinaryUnclosed writes records like a writer that crashed after
+// flushing them, leaving no index.
+func writeBinaryUnclosed(t *testing.T, w io.Writer, blockSize int, records []Record) {
+	writer := NewBinaryWriter(w)
+	writer.Compress = true
+	writer.BlockSize = blockSize
+	for _, r := range records {
+		if err := writer.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBinaryTruncated(t *testing.T) {
+	records := binaryRecords(6)
+	var buf bytes.Buffer
+	writeBinaryUnclosed(t, &buf, 3, records)
+	truncated := buf.Bytes()[:buf.Len()-3]
+
+	got, err := readBinary(NewBinaryReader(bytes.NewReader(truncated)))
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("Read error = %v; expected io.ErrUnexpectedEOF", err)
+	}
+	checkRecords(t, got, records[:3])
+
+	if _, err := ReadBinaryIndex(bytes.NewReader(truncated)); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadBinaryIndex error = %v; expected io.ErrUnexpectedEOF", err)
+	}
+}
+
+// countingReadSeeker counts the bytes read.
+type countingReadSeeker struct {
+	io.ReadSeeker
+	n int
+}
+
+func (r *countingReadSeeker) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestReadBinaryIndex(t *testing.T) {
+	records := binaryRecords(40)
+	v1 := binaryV1(records[:1])
+
+	cases := []struct {
+		name  string
+		write func(w *bytes.Buffer)
+	}{
+		{"closed", func(w *bytes.Buffer) {
+			writeBinary(t, w, false, 2, records)
+		}},
+		{"appended", func(w *bytes.Buffer) {
+			writeBinary(t, w, true, 3, records[:10])
+			writeBinary(t, w, false, 4, records[10:])
+		}},
+		{"appended to version 1", func(w *bytes.Buffer) {
+			w.Write(v1)
+			writeBinary(t, w, false, 2, records[1:])
+		}},
+		{"appended to unclosed", func(w *bytes.Buffer) {
+			writeBinaryUnclosed(t, w, 3, records[:10])
+			writeBinary(t, w, false, 2, records[10:])
+		}},
+		{"unclosed", func(w *bytes.Buffer) {
+			writeBinary(t, w, false, 3, records[:10])
+			writeBinaryUnclosed(t, w, 3, records[10:])
+		}},
+		{"empty writer", func(w *bytes.Buffer) {
+			writeBinary(t, w, false, 2, records)
+			writeBinary(t, w, false, 2, nil)
+		}},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		c.write(&buf)
+		file := buf.Bytes()
+
+		got, err := readBinary(NewBinaryReader(bytes.NewReader(file)))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		checkRecords(t, got, records)
+
+		want, err := scanBinaryIndex(bytes.NewReader(file), int64(len(file)))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		blocks, err := ReadBinaryIndex(bytes.NewReader(file))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(blocks, want) {
+			t.Errorf("%s: ReadBinaryIndex = %+v; expected %+v", c.name, blocks, want)
+		}
+		count := 0
+		for _, b := range blocks {
+			count += b.Count
+		}
+		if count != len(records) {
+			t.Errorf("%s: blocks have %d records; expected %d", c.name, count, len(records))
+		}
+	}
+}
+
+func TestReadBinaryIndexFooter(t *testing.T) {
+	records := binaryRecords(200)
+	var buf bytes.Buffer
+	writeBinary(t, &buf, false, 2, records)
+	file := buf.Bytes()
+
+	// Only the index at the end is read.
+	r := &countingReadSeeker{ReadSeeker: bytes.NewReader(file)}
+	blocks, err := ReadBinaryIndex(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 100 {
+		t.Fatalf("ReadBinaryIndex found %d blocks; expected 100", len(blocks))
+	}
+	if r.n > len(file)/4 {
+		t.Errorf("ReadBinaryIndex read %d of %d bytes", r.n, len(file))
+	}
+
+	// A damaged index falls back to scanning the blocks.
+	damaged := append([]byte(nil), file...)
+	damaged[len(damaged)-binaryTrailerLen] ^= 0xff
+	got, err := ReadBinaryIndex(bytes.NewReader(damaged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, blocks) {
+		t.Errorf("ReadBinaryIndex with a damaged index = %+v; expected %+v", got, blocks)
+	}
+}
+
+func TestSeekBinary(t *testing.T) {
+	records := binaryRecords(9)
+	var buf bytes.Buffer
+	writeBinary(t, &buf, true, 3, records)
+	file := bytes.NewReader(buf.Bytes())
+
+	cases := []struct {
+		t     time.Time
+		first int
+	}{
+		{binaryStart.Add(-time.Hour), 0},
+		{binaryStart, 0},
+		{binaryStart.Add(2 * time.Second), 0},
+		// Exactly at the start of the second and third blocks.
+		{binaryStart.Add(3 * time.Second), 3},
+		{binaryStart.Add(5500 * time.Millisecond), 3},
+		{binaryStart.Add(6 * time.Second), 6},
+		{binaryStart.Add(time.Hour), 6},
+	}
+	for _, c := range cases {
+		r, err := SeekBinary(file, c.t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := readBinary(r)
+		if err != nil {
+			t.Fatalf("SeekBinary(%s): %v", c.t, err)
+		}
+		checkRecords(t, got, records[c.first:])
+	}
+}
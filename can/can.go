@@ -3,38 +3,326 @@ package can
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"math"
 	"strconv"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 const (
 	PedalPositionPctKey = "pedal_position_pct"
 	SignedSpeedKey      = "signed_speed"
+	OdometerMilesKey    = "odometer_miles"
+	TotalDischargeKey   = "total_discharge_kwh"
+	TotalChargeKey      = "total_charge_kwh"
+	ElevationKey        = "elevation_meter"
+	FrontPowerKey       = "front_power_kw"
+	RearPowerKey        = "rear_power_kw"
+	FrontTorqueKey      = "front_torque_actual_nm"
+	RearTorqueKey       = "rear_torque_actual_nm"
+	FrontAxleRPMKey     = "front_axel_rpm"
+	RearAxleRPMKey      = "rear_axel_rpm"
 
+	// GearKey values match the gear value table in DefaultDBC.
 	GearKey     = "gear"
-	GearDrive   = 4
+	GearPark    = 1
 	GearReverse = 2
+	GearNeutral = 3
+	GearDrive   = 4
+)
+
+// ByteOrder is the bit ordering of a field within a frame.
+type ByteOrder int
+
+const (
+	// LittleEndian is the Intel byte order (@1 in DBC files).
+	LittleEndian ByteOrder = iota
+	// BigEndian is the Motorola byte order (@0 in DBC files).
+	BigEndian
+)
+
+const (
+	// MaxStandardID is the largest 11 bit ID.
+	MaxStandardID = 0x7FF
+	// MaxExtendedID is the largest 29 bit ID.
+	MaxExtendedID = 0x1FFFFFFF
+
+	// MaxDataLen is the largest payload of a classic CAN frame.
+	MaxDataLen = 8
+	// MaxFDDataLen is the largest payload of a CAN FD frame.
+	MaxFDDataLen = 64
 )
 
+// fdDataLens are the payload lengths of the CAN FD data length codes above 8.
+var fdDataLens = [...]int{12, 16, 20, 24, 32, 48, 64}
+
 type Frame struct {
-	ID        int // 29 bit if ide set, 11 bit otherwise
-	Data      [8]byte
+	// ID is 29 bits if Extended is set, 11 bits otherwise.
+	ID       int
+	Extended bool
+	// Remote is set for remote transmission requests. They carry no data,
+	// Data is zero filled to the requested length.
+	Remote bool
+	// Error is set for error frames, ID holds the error class.
+	Error bool
+	// FD is set for CAN FD frames which can carry up to 64 bytes.
+	FD bool
+	// Data is the payload, its length is the number of data bytes.
+	Data      []byte
+	Timestamp int
+}
+
+// Key returns the key of the message the frame belongs to.
+func (cf Frame) Key() MessageKey {
+	return MessageKey{ID: cf.ID, Extended: cf.Extended}
+}
+
+// DLC returns the data length code for the payload length.
+func (cf Frame) DLC() int {
+	n := len(cf.Data)
+	if n <= MaxDataLen {
+		return n
+	}
+	for i, l := range fdDataLens {
+		if n <= l {
+			return MaxDataLen + 1 + i
+		}
+	}
+	return MaxDataLen + len(fdDataLens)
+}
+
+// DLCToLen returns the payload length for a data length code.
+func DLCToLen(dlc int, fd bool) int {
+	switch {
+	case dlc <= MaxDataLen:
+		return dlc
+	case !fd:
+		return MaxDataLen
+	case dlc-MaxDataLen-1 < len(fdDataLens):
+		return fdDataLens[dlc-MaxDataLen-1]
+	default:
+		return MaxFDDataLen
+	}
+}
+
+// ValidDataLen reports whether n is a valid payload length for a classic or,
+// if fd is set, a CAN FD frame.
+func ValidDataLen(n int, fd bool) bool {
+	if n >= 0 && n <= MaxDataLen {
+		return true
+	}
+	if !fd {
+		return false
+	}
+	for _, l := range fdDataLens {
+		if n == l {
+			return true
+		}
+	}
+	return false
+}
+
+// frameJSON is the JSON encoding of a Frame. Data is an array of numbers, as
+// it was when frames had a fixed 8 byte array, rather than the base64 string
+// encoding/json uses for byte slices.
+type frameJSON struct {
+	ID        int
+	Extended  bool `json:",omitempty"`
+	Remote    bool `json:",omitempty"`
+	Error     bool `json:",omitempty"`
+	FD        bool `json:",omitempty"`
+	Data      json.RawMessage
 	Timestamp int
 }
 
+func (cf Frame) MarshalJSON() ([]byte, error) {
+	data := make([]int, len(cf.Data))
+	for i, b := range cf.Data {
+		data[i] = int(b)
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(frameJSON{
+		ID:        cf.ID,
+		Extended:  cf.Extended,
+		Remote:    cf.Remote,
+		Error:     cf.Error,
+		FD:        cf.FD,
+		Data:      raw,
+		Timestamp: cf.Timestamp,
+	})
+}
+
+// UnmarshalJSON decodes frames with Data as either an array of numbers or a
+// base64 string. Frames logged before the Extended flag existed are marked
+// extended if their ID doesn't fit in 11 bits.
+func (cf *Frame) UnmarshalJSON(b []byte) error {
+	var f frameJSON
+	if err := json.Unmarshal(b, &f); err != nil {
+		return err
+	}
+	var data []byte
+	if trimmed := bytes.TrimSpace(f.Data); len(trimmed) > 0 && trimmed[0] == '"' {
+		if err := json.Unmarshal(trimmed, &data); err != nil {
+			return err
+		}
+	} else if len(trimmed) > 0 && !bytes.Equal(trimmed, []byte("null")) {
+		var values []int
+		if err := json.Unmarshal(trimmed, &values); err != nil {
+			return err
+		}
+		data = make([]byte, len(values))
+		for i, v := range values {
+			if v < 0 || v > 0xFF {
+				return errors.Errorf("invalid data byte %d", v)
+			}
+			data[i] = byte(v)
+		}
+	}
+	if len(data) > MaxFDDataLen {
+		return errors.Errorf("frame data too long: %d bytes", len(data))
+	}
+	*cf = Frame{
+		ID:        f.ID,
+		Extended:  f.Extended || f.ID > MaxStandardID,
+		Remote:    f.Remote,
+		Error:     f.Error,
+		FD:        f.FD,
+		Data:      data,
+		Timestamp: f.Timestamp,
+	}
+	return nil
+}
+
+// Uint64 returns the first 8 bytes of the payload as a little endian number.
 func (cf Frame) Uint64() uint64 {
-	r := bytes.NewReader(cf.Data[:])
-	var v uint64
-	if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
-		panic(err)
+	var b [8]byte
+	copy(b[:], cf.Data)
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// Uint64BigEndian returns the first 8 bytes of the payload as a big endian
+// number.
+func (cf Frame) Uint64BigEndian() uint64 {
+	var b [8]byte
+	copy(b[:], cf.Data)
+	return binary.BigEndian.Uint64(b[:])
+}
+
+// byteAt returns the data byte at i, bytes past the end of the payload read as
+// 0.
+func (cf Frame) byteAt(i int) uint64 {
+	if i < 0 || i >= len(cf.Data) {
+		return 0
 	}
-	return v
+	return uint64(cf.Data[i])
 }
 
+// ReadBits reads an unsigned little endian field of n bits where start is the
+// least significant bit.
 func (cf Frame) ReadBits(n, start int) int64 {
-	b := cf.Uint64()
-	return int64((b << (64 - n - start)) >> (64 - n))
+	return int64(cf.readLittleEndian(n, start))
+}
+
+// ReadSignedBits reads a two's complement little endian field of n bits where
+// start is the least significant bit.
+func (cf Frame) ReadSignedBits(n, start int) int64 {
+	return signExtend(cf.readLittleEndian(n, start), n)
+}
+
+func (cf Frame) readLittleEndian(n, start int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	i := start / 8
+	v := cf.byteAt(i) >> uint(start%8)
+	for got := 8 - start%8; got < n; got += 8 {
+		i++
+		v |= cf.byteAt(i) << uint(got)
+	}
+	return mask(v, n)
+}
+
+// bigEndianShift converts a DBC big endian start bit, which is the most
+// significant bit of the field counted LSB first within each byte, into the
+// number of bits before it in the big endian representation of the frame.
+func bigEndianShift(start int) int {
+	return start/8*8 + 7 - start%8
+}
+
+// ReadBitsBigEndian reads an unsigned big endian (Motorola) field of n bits
+// where start is the most significant bit using DBC bit numbering.
+func (cf Frame) ReadBitsBigEndian(n, start int) int64 {
+	return int64(cf.readBigEndian(n, start))
+}
+
+// ReadSignedBitsBigEndian reads a two's complement big endian (Motorola) field
+// of n bits where start is the most significant bit using DBC bit numbering.
+func (cf Frame) ReadSignedBitsBigEndian(n, start int) int64 {
+	return signExtend(cf.readBigEndian(n, start), n)
+}
+
+func (cf Frame) readBigEndian(n, start int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	i := start / 8
+	// The field starts with the bits at and below start in the first byte.
+	got := start%8 + 1
+	v := mask(cf.byteAt(i), got)
+	if got >= n {
+		return v >> uint(got-n)
+	}
+	for got < n {
+		i++
+		if need := n - got; need < 8 {
+			v = v<<uint(need) | cf.byteAt(i)>>uint(8-need)
+			got += need
+		} else {
+			v = v<<8 | cf.byteAt(i)
+			got += 8
+		}
+	}
+	return v
+}
+
+// mask keeps the low n bits of v.
+func mask(v uint64, n int) uint64 {
+	if n >= 64 {
+		return v
+	}
+	return v & (1<<uint(n) - 1)
+}
+
+// signExtend interprets the low n bits of v as a two's complement number.
+func signExtend(v uint64, n int) int64 {
+	if n <= 0 || n >= 64 {
+		return int64(v)
+	}
+	shift := uint(64 - n)
+	return int64(v<<shift) >> shift
+}
+
+func (cf Frame) readUint(n, start int, order ByteOrder) uint64 {
+	if order == BigEndian {
+		return cf.readBigEndian(n, start)
+	}
+	return cf.readLittleEndian(n, start)
+}
+
+// ReadIEEEFloat32 reads a 32 bit IEEE 754 floating point field.
+func (cf Frame) ReadIEEEFloat32(start int, order ByteOrder) float32 {
+	return math.Float32frombits(uint32(cf.readUint(32, start, order)))
+}
+
+// ReadIEEEFloat64 reads a 64 bit IEEE 754 floating point field.
+func (cf Frame) ReadIEEEFloat64(start int, order ByteOrder) float64 {
+	return math.Float64frombits(cf.readUint(64, start, order))
 }
 
 func (cf Frame) ReadFloat(n, start int, offset, scale float64) float64 {
@@ -46,24 +334,56 @@ func (cf Frame) ReadFloat(n, start int, offset, scale float64) float64 {
 }
 
 func (cf Frame) String() string {
-	return fmt.Sprintf("Frame(id=%X, data=%+v, timestamp=%d)", cf.ID, cf.Data, cf.Timestamp)
+	var flags string
+	if cf.Extended {
+		flags += ", extended"
+	}
+	if cf.Remote {
+		flags += ", remote"
+	}
+	if cf.Error {
+		flags += ", error"
+	}
+	if cf.FD {
+		flags += ", fd"
+	}
+	return fmt.Sprintf("Frame(id=%X, data=%+v, timestamp=%d%s)", cf.ID, cf.Data, cf.Timestamp, flags)
 }
 
+// csvColumns is the number of columns in a classic CSV frame: the ID, 8 data
+// bytes and the timestamp.
+const csvColumns = 10
+
+// ParseCSV parses a frame from a CSV row as streamed by the canbus device.
+// Rows with more than 8 data bytes are CAN FD frames and IDs that don't fit in
+// 11 bits are extended.
 func ParseCSV(row []string) (Frame, error) {
 	var frame Frame
-	id, err := strconv.ParseUint(row[0], 10, 64)
+	if len(row) < csvColumns {
+		return frame, errors.Errorf("expected %d columns, got %d", csvColumns, len(row))
+	}
+	n := len(row) - 2
+	if n > MaxDataLen {
+		if !ValidDataLen(n, true) {
+			return frame, errors.Errorf("invalid CAN FD data length %d", n)
+		}
+		frame.FD = true
+	}
+	id, err := strconv.ParseUint(row[0], 10, 29)
 	if err != nil {
 		return frame, err
 	}
 	frame.ID = int(id)
-	for i, b := range row[1:9] {
-		parsed, err := strconv.ParseUint(b, 10, 64)
+	frame.Extended = frame.ID > MaxStandardID
+	frame.Data = make([]byte, n)
+	for i, b := range row[1 : n+1] {
+		parsed, err := strconv.ParseUint(b, 10, 8)
 		if err != nil {
 			return frame, err
 		}
 		frame.Data[i] = byte(parsed)
 	}
-	timestamp, err := strconv.ParseUint(row[5], 10, 64)
+	timestamp, err := strconv.ParseUint(row[n+1], 10, strconv.IntSize-1)
 	if err != nil {
 		return frame, err
 	}
@@ -71,151 +391,39 @@ func ParseCSV(row []string) (Frame, error) {
 	return frame, nil
 }
 
+// FormatCSV formats the frame as a CSV row in the format read by ParseCSV:
+// the ID, the data bytes and the timestamp. Classic frames are zero padded to
+// 8 data bytes.
+func FormatCSV(frame Frame) []string {
+	n := len(frame.Data)
+	if n < MaxDataLen {
+		n = MaxDataLen
+	}
+	row := make([]string, 0, n+2)
+	row = append(row, strconv.Itoa(frame.ID))
+	for i := 0; i < n; i++ {
+		row = append(row, strconv.Itoa(int(frame.byteAt(i))))
+	}
+	row = append(row, strconv.Itoa(frame.Timestamp))
+	return row
+}
+
 // Record for recording canbus events for later analysis.
 type Record struct {
 	Frame Frame
 	Time  time.Time
+	// Bus is the name of the bus the frame was received on. It's empty when
+	// there's only one.
+	Bus string `json:",omitempty"`
 }
 
+// FrameToKV decodes the frame using the built-in signal database.
 func FrameToKV(frame Frame) map[string]float64 {
-	kv := map[string]float64{}
-	set := func(key string, value float64) {
-		kv[key] = value
-	}
-
-	switch frame.ID {
-	case 0x108:
-		set("rear_torque_request_nm", frame.ReadFloat(13, 12, 0, 0.22222))
-		set("rear_torque_actual_nm", frame.ReadFloat(13, 27, 0, 0.22222))
-		set("rear_axel_rpm", frame.ReadFloat(16, 40, 0, 0.1))
-	case 0x118:
-		set("drive_state", frame.ReadFloat(3, 16, 0, 1))
-		set("brake_pedal", frame.ReadFloat(2, 19, 0, 1))
-		set(GearKey, frame.ReadFloat(3, 21, 0, 1))
-		set("brake_hold", frame.ReadFloat(1, 26, 0, 1))
-		set("immobilizer", frame.ReadFloat(3, 27, 0, 1))
-		set(PedalPositionPctKey, frame.ReadFloat(8, 32, 0, 0.4))
-		set("traction_control", frame.ReadFloat(3, 40, 0, 1))
-		set("parking_brake", frame.ReadFloat(2, 44, 0, 1))
-		set("track_mode", frame.ReadFloat(2, 48, 0, 1))
-	case 0x129:
-		set("steering_angle_deg", frame.ReadFloat(14, 16, -819.2, 0.1))
-		set("steering_speed_dps", frame.ReadFloat(14, 32, -4096, 0.5))
-	case 0x132:
-		set("battery_voltage", frame.ReadFloat(16, 0, 0, 0.01))
-		set("battery_current", frame.ReadFloat(16, 16, 1000, -0.01))
-		set("raw_battery_current", frame.ReadFloat(16, 32, 1000, -0.05))
-		set("charge_time_remaining", frame.ReadFloat(12, 48, 0, 1))
-	case 0x186:
-		set("front_torque_request_nm", frame.ReadFloat(13, 12, 0, 0.22222))
-		set("front_torque_actual_nm", frame.ReadFloat(13, 27, 0, 0.22222))
-		set("front_axel_rpm", frame.ReadFloat(16, 40, 0, 0.1))
-	case 0x1D5:
-		set("front_torque2_request_nm", frame.ReadFloat(15, 8, 0, 0.1))
-		set("front_torque2_nm", frame.ReadFloat(13, 24, 0, 0.25))
-	case 0x1D8:
-		set("rear_torque2_request_nm", frame.ReadFloat(15, 8, 0, 0.1))
-		set("rear_torque2_nm", frame.ReadFloat(13, 24, 0, 0.25))
-	case 0x212:
-		set("bms_contactors", frame.ReadFloat(3, 8, 0, 1))
-		set("bms_state", frame.ReadFloat(4, 11, 0, 1))
-		set("isolation_restance_kohm", frame.ReadFloat(10, 19, 0, 1))
-		set("bms_charge_status", frame.ReadFloat(3, 32, 0, 1))
-		set("bms_charge_power_available_kw", frame.ReadFloat(11, 38, 0, 0.125))
-		set("min_batt_temp_c", frame.ReadFloat(8, 56, -40, 0.5))
-	case 0x229:
-		set("gear_lever_position", frame.ReadFloat(3, 12, 0, 1))
-		set("gear_lever_button", frame.ReadFloat(2, 16, 0, 1))
-	case 0x241:
-		set("battery_coolant_flow_rate_lpm", frame.ReadFloat(9, 0, 0, 0.1))
-		set("powertrain_coolant_flow_rate", frame.ReadFloat(9, 22, 0, 0.1))
-	case 0x249:
-		set("left_stalk_horizontal", frame.ReadFloat(2, 12, 0, 1))
-		set("left_stalk_button", frame.ReadFloat(2, 14, 0, 1))
-		set("left_stalk_vertical", frame.ReadFloat(3, 16, 0, 1))
-	case 0x252:
-		set("regen_power_limit_kw", frame.ReadFloat(16, 0, 0, 0.01))
-		set("discharge_power_limit_kw", frame.ReadFloat(16, 16, 0, 0.01))
-		set("max_heat_parked_kw", frame.ReadFloat(10, 32, 0, 0.1))
-		set("hvac_max_power_kw", frame.ReadFloat(10, 50, 0, 0.02))
-	case 0x257:
-		set(SignedSpeedKey, frame.ReadFloat(12, 12, -25, 0.05))
-		set("ui_speed", frame.ReadFloat(8, 24, 0, 1))
-		set("mph_kph_flag", frame.ReadFloat(1, 32, 0, 1))
-	case 0x261:
-		set("12v_battery_voltage", frame.ReadFloat(12, 0, 0, 0.005444))
-		set("12v_battery_temp_c", frame.ReadFloat(16, 16, 0, 0.01))
-		set("12v_battery_amp_hours", frame.ReadFloat(14, 32, 0, 0.01))
-		set("12v_battery_current_amp", frame.ReadFloat(16, 48, 0, 0.005))
-	case 0x264:
-		set("charge_line_voltage", frame.ReadFloat(14, 0, 0, 0.0333))
-		set("charge_line_current_amp", frame.ReadFloat(9, 14, 0, 0.1))
-		set("charge_line_power_kw", frame.ReadFloat(8, 24, 0, 0.1))
-		set("charge_line_current_limit_amp", frame.ReadFloat(10, 32, 0, 0.1))
-	case 0x266:
-		set("rear_power_kw", frame.ReadFloat(11, 0, 0, 0.5))
-		set("rear_heat_power_optimal_kw", frame.ReadFloat(8, 32, 0, 0.08))
-		set("rear_heat_power_max_kw", frame.ReadFloat(8, 40, 0, 0.08))
-		set("rear_heat_power_kw", frame.ReadFloat(8, 48, 0, 0.08))
-	case 0x292:
-		set("ui_state_of_charge_pct", frame.ReadFloat(10, 0, 0, 0.1))
-		set("min_state_of_charge_pct", frame.ReadFloat(10, 10, 0, 0.1))
-		set("max_state_of_charge_pct", frame.ReadFloat(10, 20, 0, 0.1))
-		set("average_state_of_charge_pct", frame.ReadFloat(10, 30, 0, 0.1))
-	case 0x2E5:
-		set("front_power_kw", frame.ReadFloat(11, 0, 0, 0.5))
-		set("front_heat_power_optimal_kw", frame.ReadFloat(8, 32, 0, 0.08))
-		set("front_heat_power_max_kw", frame.ReadFloat(8, 40, 0, 0.08))
-		set("front_heat_power_kw", frame.ReadFloat(8, 48, 0, 0.08))
-	case 0x293:
-		set("ui_steering_mode", frame.ReadFloat(2, 0, 0, 1))
-		set("ui_traction_control_mode", frame.ReadFloat(3, 2, 0, 1))
-	case 0x321:
-		set("coolant_temp_battery_inlet_c", frame.ReadFloat(10, 0, -40, 0.125))
-		set("coolant_temp_powertrain_inlet_c", frame.ReadFloat(10, 10, -40, 0.125))
-		set("ambient_temp_raw_c", frame.ReadFloat(8, 24, -40, 0.5))
-		set("ambient_temp_filtered_c", frame.ReadFloat(8, 40, -40, 0.5))
-	case 0x333:
-		set("ui_charge_current_limit_amp", frame.ReadFloat(7, 8, 0, 1))
-		set("ui_charge_limit_pct", frame.ReadFloat(10, 16, 0, 0.1))
-	case 0x336:
-		set("power_rating_kw", frame.ReadFloat(9, 0, 0, 1))
-		set("regen_rating_kw", frame.ReadFloat(8, 16, -100, 1))
-	case 0x352:
-		set("full_battery_capacity_kwh", frame.ReadFloat(10, 0, 0, 0.1))
-		set("remaining_battery_chage_kwh", frame.ReadFloat(10, 10, 0, 0.1))
-		set("expected_remaining_kwh", frame.ReadFloat(10, 20, 0, 0.1))
-		set("ideal_remaining_kwh", frame.ReadFloat(10, 30, 0, 0.1))
-		set("kwh_to_complete_charge", frame.ReadFloat(10, 40, 0, 0.1))
-		set("energy_buffer_kwh", frame.ReadFloat(10, 50, 0, 0.1))
-	case 0x376:
-		set("inverter_pcb_temp_c", frame.ReadFloat(8, 0, -40, 1))
-		set("inverter_temp_c", frame.ReadFloat(8, 8, -40, 1))
-		set("stator_temp_c", frame.ReadFloat(8, 16, -40, 1))
-		set("inverter_capbank_temp_c", frame.ReadFloat(8, 24, -40, 1))
-		set("inverter_heatsink_temp_c", frame.ReadFloat(8, 32, -40, 1))
-		set("inverter_temp_pct", frame.ReadFloat(8, 40, 0, 0.4))
-		set("stator_temp_pct", frame.ReadFloat(8, 48, 0, 0.4))
-	case 0x396:
-		set("rear_oil_pump_state", frame.ReadFloat(3, 0, 0, 1))
-		set("rear_oil_flow_target_lpm", frame.ReadFloat(8, 8, 0, 0.06))
-		set("rear_oil_flow_actual_lpm", frame.ReadFloat(8, 16, 0, 0.06))
-	case 0x3B6:
-		set("odometer_meter", frame.ReadFloat(32, 0, 0, 0.001))
-		set("odometer_miles", frame.ReadFloat(32, 0, 0, 0.000621371))
-	case 0x3D2:
-		set("total_discharge_kwh", frame.ReadFloat(32, 0, 0, 0.001))
-		set("total_charge_kwh", frame.ReadFloat(32, 32, 0, 0.001))
-	case 0x3D8:
-		set("elevation_meter", frame.ReadFloat(16, 0, 0, 1))
-	case 0x3FE:
-		set("front_left_brake_temp", frame.ReadFloat(10, 0, -40, 1))
-		set("front_right_brake_temp", frame.ReadFloat(10, 10, -40, 1))
-		set("rear_left_brake_temp", frame.ReadFloat(10, 20, -40, 1))
-		set("rear_right_brake_temp", frame.ReadFloat(10, 30, -40, 1))
-	case 0x541:
-		set("fast_charge_max_power_limit_kw", frame.ReadFloat(13, 0, 0, 0.062256))
-		set("fast_charge_max_current_limit_amp", frame.ReadFloat(16, 16, 0, 0.073242))
-	}
-	return kv
+	return DefaultDBC.Decode(frame)
+}
+
+// FrameToValues decodes the frame using the built-in signal database and
+// returns the values along with their signal descriptors.
+func FrameToValues(frame Frame) []Value {
+	return DefaultDBC.DecodeValues(frame)
 }
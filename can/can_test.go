@@ -0,0 +1,145 @@
+package can
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Frame
+		err  bool
+	}{
+		{
+			in:   "280,1,2,3,4,5,6,7,8,123456",
+			want: Frame{ID: 280, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}, Timestamp: 123456},
+		},
+		{
+			in:   "305419896,0,0,0,0,0,0,0,255,0",
+			want: Frame{ID: 0x12345678, Extended: true, Data: []byte{7: 255}},
+		},
+		{
+			in:   "1,1,2,3,4,5,6,7,8,9,10,11,12,5",
+			want: Frame{ID: 1, FD: true, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, Timestamp: 5},
+		},
+		{in: "", err: true},
+		{in: "280,1,2,3,4,5", err: true},
+		{in: "280,1,2,3,4,5,6,7,8", err: true},
+		{in: "280,1,2,3,4,5,6,7,8,9,0", err: true},
+		{in: "280,1,2,3,4,5,6,7,256,0", err: true},
+		{in: "280,1,2,3,4,5,6,7,-1,0", err: true},
+		{in: "536870912,1,2,3,4,5,6,7,8,0", err: true},
+		{in: "x,1,2,3,4,5,6,7,8,0", err: true},
+		{in: "280,1,2,3,4,5,6,7,8,x", err: true},
+	}
+	for _, c := range cases {
+		out, err := ParseCSV(strings.Split(c.in, ","))
+		if (err != nil) != c.err {
+			t.Errorf("ParseCSV(%q) error = %v; expected error %t", c.in, err, c.err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(out, c.want) {
+			t.Errorf("ParseCSV(%q) = %+v; expected %+v", c.in, out, c.want)
+		}
+	}
+}
+
+func TestFormatCSV(t *testing.T) {
+	frame := Frame{ID: 0x3D2, Data: []byte{0, 1, 127, 128, 200, 254, 255, 9}, Timestamp: 42}
+	out, err := ParseCSV(FormatCSV(frame))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, frame) {
+		t.Errorf("ParseCSV(FormatCSV(%+v)) = %+v", frame, out)
+	}
+}
+
+func TestReadBits(t *testing.T) {
+	frame := Frame{Data: []byte{0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0, 0x0F, 0xFF}}
+	cases := []struct {
+		name     string
+		read     func(n, start int) int64
+		n, start int
+		want     int64
+	}{
+		{"ReadBits", frame.ReadBits, 8, 0, 0x12},
+		{"ReadBits", frame.ReadBits, 16, 8, 0x5634},
+		{"ReadBits", frame.ReadBits, 4, 4, 0x1},
+		{"ReadBits", frame.ReadBits, 12, 12, 0x563},
+		{"ReadBits", frame.ReadBits, 32, 32, 0xF0DEBC9A},
+		{"ReadSignedBits", frame.ReadSignedBits, 8, 56, -16},
+		{"ReadSignedBits", frame.ReadSignedBits, 8, 0, 0x12},
+		{"ReadSignedBits", frame.ReadSignedBits, 4, 60, -1},
+		{"ReadBitsBigEndian", frame.ReadBitsBigEndian, 8, 7, 0x12},
+		{"ReadBitsBigEndian", frame.ReadBitsBigEndian, 16, 7, 0x1234},
+		{"ReadBitsBigEndian", frame.ReadBitsBigEndian, 12, 3, 0x234},
+		{"ReadBitsBigEndian", frame.ReadBitsBigEndian, 8, 63, 0xF0},
+		{"ReadSignedBitsBigEndian", frame.ReadSignedBitsBigEndian, 8, 63, -16},
+		{"ReadSignedBitsBigEndian", frame.ReadSignedBitsBigEndian, 16, 7, 0x1234},
+		{"ReadBits", frame.ReadBits, 12, 60, 0x0FF},
+		{"ReadBits", frame.ReadBits, 16, 72, 0xFF},
+		{"ReadBitsBigEndian", frame.ReadBitsBigEndian, 16, 67, 0xFFF0},
+		{"ReadSignedBitsBigEndian", frame.ReadSignedBitsBigEndian, 12, 67, -1},
+	}
+	for _, c := range cases {
+		if out := c.read(c.n, c.start); out != c.want {
+			t.Errorf("%s(%d, %d) = %#x; expected %#x", c.name, c.n, c.start, out, c.want)
+		}
+	}
+}
+
+func TestReadFloat(t *testing.T) {
+	frame := Frame{Data: []byte{0xE8, 0x03}}
+	if out := frame.ReadFloat(16, 0, -40, 0.1); out != 60 {
+		t.Errorf("ReadFloat(16, 0, -40, 0.1) = %f; expected 60", out)
+	}
+}
+
+func TestDLC(t *testing.T) {
+	for n := 0; n <= MaxFDDataLen; n++ {
+		fd := n > MaxDataLen
+		frame := Frame{FD: fd, Data: make([]byte, n)}
+		if ValidDataLen(n, fd) != (DLCToLen(frame.DLC(), fd) == n) {
+			t.Errorf("DLCToLen(%d, %t) = %d for valid length %t", frame.DLC(), fd, DLCToLen(frame.DLC(), fd), ValidDataLen(n, fd))
+		}
+	}
+	if out := DLCToLen(15, false); out != 8 {
+		t.Errorf("DLCToLen(15, false) = %d; expected 8", out)
+	}
+}
+
+func TestFrameJSON(t *testing.T) {
+	// Frames logged when Data was a fixed size array.
+	var old Frame
+	if err := json.Unmarshal([]byte(`{"ID":305419896,"Data":[1,2,3,4,5,6,7,8],"Timestamp":1}`), &old); err != nil {
+		t.Fatal(err)
+	}
+	want := Frame{ID: 0x12345678, Extended: true, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}, Timestamp: 1}
+	if !reflect.DeepEqual(old, want) {
+		t.Errorf("json.Unmarshal = %+v; expected %+v", old, want)
+	}
+
+	frames := []Frame{
+		{ID: 0x118, Data: []byte{1, 2}},
+		{ID: 0x1, Extended: true, Remote: true, Data: make([]byte, 4)},
+		{ID: 0x4, Error: true, Data: make([]byte, 8)},
+		{ID: 0x3D2, FD: true, Data: make([]byte, 64)},
+	}
+	for _, frame := range frames {
+		b, err := json.Marshal(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out Frame
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out, frame) {
+			t.Errorf("json round trip of %+v = %+v, encoded %s", frame, out, b)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package can
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CandumpReader reads candump -L logs such as:
+//
+//	(1436509052.249713) can0 118#0000800000000000
+//
+// The interface name is used as the bus of each record.
+type CandumpReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func NewCandumpReader(r io.Reader) *CandumpReader {
+	return &CandumpReader{s: bufio.NewScanner(r)}
+}
+
+func (r *CandumpReader) Read() (Record, error) {
+	for r.s.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.s.Text())
+		if line == "" {
+			continue
+		}
+		record, err := parseCandumpLine(line)
+		if err != nil {
+			return Record{}, errors.Wrapf(err, "line %d", r.line)
+		}
+		return record, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+// candumpErrFlag marks error frames in candump IDs.
+const candumpErrFlag = 0x20000000
+
+// parseCandumpLine parses a single log line. Frames are written as ID#data,
+// ID#R for remote frames with an optional length and ID##<flags>data for CAN
+// FD frames. 8 digit IDs are extended.
+func parseCandumpLine(line string) (Record, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[0], "(") || !strings.HasSuffix(fields[0], ")") {
+		return Record{}, errors.Errorf("invalid candump line %q", line)
+	}
+	t, err := parseUnixSeconds(strings.Trim(fields[0], "()"))
+	if err != nil {
+		return Record{}, err
+	}
+
+	parts := strings.SplitN(fields[2], "#", 2)
+	if len(parts) != 2 {
+		return Record{}, errors.Errorf("invalid candump frame %q", fields[2])
+	}
+	id, err := strconv.ParseUint(parts[0], 16, 32)
+	if err != nil {
+		return Record{}, err
+	}
+	var frame Frame
+	switch {
+	case id&candumpErrFlag != 0:
+		frame.Error = true
+		frame.ID = int(id &^ candumpErrFlag)
+	case len(parts[0]) == 8:
+		if id > MaxExtendedID {
+			return Record{}, errors.Errorf("invalid extended ID %q", parts[0])
+		}
+		frame.Extended = true
+		frame.ID = int(id)
+	default:
+		if id > MaxStandardID {
+			return Record{}, errors.Errorf("invalid standard ID %q", parts[0])
+		}
+		frame.ID = int(id)
+	}
+
+	payload := parts[1]
+	switch {
+	case strings.HasPrefix(payload, "R"):
+		frame.Remote = true
+		n := 0
+		if len(payload) > 1 {
+			if n, err = strconv.Atoi(payload[1:]); err != nil {
+				return Record{}, err
+			}
+			if !ValidDataLen(n, false) {
+				return Record{}, errors.Errorf("invalid remote frame length %d", n)
+			}
+		}
+		frame.Data = make([]byte, n)
+		return Record{Frame: frame, Time: t, Bus: fields[1]}, nil
+	case strings.HasPrefix(payload, "#"):
+		// The flags nibble holds the bit rate switch and error state
+		// indicator, which aren't recorded.
+		if len(payload) < 2 {
+			return Record{}, errors.Errorf("invalid CAN FD frame %q", fields[2])
+		}
+		frame.FD = true
+		payload = payload[2:]
+	}
+	data, err := hex.DecodeString(strings.Replace(payload, ".", "", -1))
+	if err != nil {
+		return Record{}, err
+	}
+	if !ValidDataLen(len(data), frame.FD) {
+		return Record{}, errors.Errorf("invalid data length %d", len(data))
+	}
+	frame.Data = data
+	return Record{Frame: frame, Time: t, Bus: fields[1]}, nil
+}
+
+// parseUnixSeconds parses a decimal number of seconds since the epoch.
+func parseUnixSeconds(s string) (time.Time, error) {
+	parts := strings.SplitN(s, ".", 2)
+	sec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var nsec int64
+	if len(parts) == 2 {
+		frac := (parts[1] + "000000000")[:9]
+		if nsec, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+// CandumpWriter writes candump -L logs.
+type CandumpWriter struct {
+	w *bufio.Writer
+	// Interface is the interface name written on lines for records without a
+	// bus.
+	Interface string
+}
+
+func NewCandumpWriter(w io.Writer) *CandumpWriter {
+	return &CandumpWriter{w: bufio.NewWriter(w), Interface: "can0"}
+}
+
+func (w *CandumpWriter) Write(record Record) error {
+	frame := record.Frame
+	id := formatID(frame.ID)
+	if frame.Extended {
+		id = fmt.Sprintf("%08X", frame.ID)
+	}
+	if frame.Error {
+		id = fmt.Sprintf("%08X", frame.ID|candumpErrFlag)
+	}
+	var payload string
+	switch {
+	case frame.Remote && len(frame.Data) > 0:
+		payload = fmt.Sprintf("R%d", len(frame.Data))
+	case frame.Remote:
+		payload = "R"
+	case frame.FD:
+		payload = fmt.Sprintf("#0%X", frame.Data)
+	default:
+		payload = fmt.Sprintf("%X", frame.Data)
+	}
+	iface := w.Interface
+	if record.Bus != "" {
+		iface = record.Bus
+	}
+	_, err := fmt.Fprintf(
+		w.w, "(%d.%06d) %s %s#%s\n",
+		record.Time.Unix(), record.Time.Nanosecond()/1000, iface, id, payload,
+	)
+	return err
+}
+
+func (w *CandumpWriter) Close() error {
+	return w.w.Flush()
+}
+
+// formatID formats standard IDs as 3 hex digits and extended IDs as 8.
+func formatID(id int) string {
+	if id > 0x7FF {
+		return fmt.Sprintf("%08X", id)
+	}
+	return fmt.Sprintf("%03X", id)
+}
@@ -0,0 +1,491 @@
+package can
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ValueType is the encoding of a signal's raw value.
+type ValueType int
+
+const (
+	// Integer signals are two's complement when signed.
+	Integer ValueType = iota
+	// Float32 signals are 32 bit IEEE 754 values (SIG_VALTYPE_ 1).
+	Float32
+	// Float64 signals are 64 bit IEEE 754 values (SIG_VALTYPE_ 2).
+	Float64
+)
+
+// Signal describes how to extract a single value from a frame.
+type Signal struct {
+	Name      string
+	Start     int
+	Length    int
+	ByteOrder ByteOrder
+	Signed    bool
+	ValueType ValueType
+
+	// Multiplexor is set for the signal that selects which multiplexed
+	// signals are present in a message.
+	Multiplexor bool
+	// Multiplexed signals are only present when the multiplexor equals
+	// MuxValue.
+	Multiplexed bool
+	MuxValue    int
+	Factor      float64
+	Offset      float64
+	Min         float64
+	Max         float64
+	Unit        string
+
+	// Description is the signal comment from the DBC file.
+	Description string
+	// Values maps raw values to labels for enumerated signals.
+	Values map[int]string
+}
+
+// Raw extracts the unscaled value of the signal from the frame.
+func (s *Signal) Raw(frame Frame) float64 {
+	switch s.ValueType {
+	case Float32:
+		return float64(frame.ReadIEEEFloat32(s.Start, s.ByteOrder))
+	case Float64:
+		return frame.ReadIEEEFloat64(s.Start, s.ByteOrder)
+	}
+
+	switch {
+	case s.ByteOrder == BigEndian && s.Signed:
+		return float64(frame.ReadSignedBitsBigEndian(s.Length, s.Start))
+	case s.ByteOrder == BigEndian:
+		return float64(frame.ReadBitsBigEndian(s.Length, s.Start))
+	case s.Signed:
+		return float64(frame.ReadSignedBits(s.Length, s.Start))
+	default:
+		return float64(frame.ReadBits(s.Length, s.Start))
+	}
+}
+
+// Decode extracts the scaled value of the signal from the frame.
+func (s *Signal) Decode(frame Frame) float64 {
+	return s.Raw(frame)*s.Factor + s.Offset
+}
+
+// Label returns the enum label for the value if the signal has one.
+func (s *Signal) Label(value float64) (string, bool) {
+	if len(s.Values) == 0 || value != math.Trunc(value) {
+		return "", false
+	}
+	label, ok := s.Values[int(value)]
+	return label, ok
+}
+
+// Help describes the signal and its unit for use in metric help strings.
+func (s *Signal) Help() string {
+	help := s.Description
+	if help == "" {
+		help = s.Name
+		if group, _, ok := s.MuxGroup(); ok {
+			help = group
+		}
+	}
+	if s.Unit != "" {
+		help += " Unit: " + s.Unit + "."
+	}
+	return help
+}
+
+// Value is a decoded signal value.
+type Value struct {
+	Signal *Signal
+	Value  float64
+}
+
+// String formats the value with its enum label or unit.
+func (v Value) String() string {
+	if label, ok := v.Signal.Label(v.Value); ok {
+		return label
+	}
+	if v.Signal.Unit == "" {
+		return fmt.Sprintf("%f", v.Value)
+	}
+	return fmt.Sprintf("%f %s", v.Value, v.Signal.Unit)
+}
+
+// muxIndexRegexp matches the trailing index of a multiplexed signal group.
+var muxIndexRegexp = regexp.MustCompile(`^(\w+?)_(\d+)$`)
+
+// MuxGroup splits the name of a multiplexed signal such as brick_voltage_42
+// into the name of its group and the index within it.
+func (s *Signal) MuxGroup() (string, int, bool) {
+	if !s.Multiplexed {
+		return "", 0, false
+	}
+	matches := muxIndexRegexp.FindStringSubmatch(s.Name)
+	if matches == nil {
+		return "", 0, false
+	}
+	index, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return "", 0, false
+	}
+	return matches[1], index, true
+}
+
+// Message is a set of signals that share a frame ID.
+type Message struct {
+	ID       int
+	Extended bool
+	Name     string
+	// DLC is the data length in bytes, up to 64 for CAN FD messages.
+	DLC     int
+	Signals []*Signal
+}
+
+// Multiplexor returns the multiplexor signal of the message or nil if it isn't
+// multiplexed.
+func (m *Message) Multiplexor() *Signal {
+	for _, signal := range m.Signals {
+		if signal.Multiplexor {
+			return signal
+		}
+	}
+	return nil
+}
+
+// Signal returns the signal with the given name or nil if there isn't one.
+func (m *Message) Signal(name string) *Signal {
+	for _, signal := range m.Signals {
+		if signal.Name == name {
+			return signal
+		}
+	}
+	return nil
+}
+
+// MessageKey identifies a message. Standard and extended frames with the same
+// ID are different messages.
+type MessageKey struct {
+	ID       int
+	Extended bool
+}
+
+// Key returns the key of the message.
+func (m *Message) Key() MessageKey {
+	return MessageKey{ID: m.ID, Extended: m.Extended}
+}
+
+// DBC is a database of message and signal definitions.
+type DBC struct {
+	Messages map[MessageKey]*Message
+}
+
+// Signal returns the signal with the given name from any message or nil if
+// there isn't one.
+func (d *DBC) Signal(name string) *Signal {
+	for _, msg := range d.Messages {
+		if signal := msg.Signal(name); signal != nil {
+			return signal
+		}
+	}
+	return nil
+}
+
+// DecodeValues returns the values of all signals defined for the frame ID
+// along with their descriptors. For multiplexed messages only the signals
+// selected by the multiplexor are returned. Remote and error frames have no
+// values.
+func (d *DBC) DecodeValues(frame Frame) []Value {
+	if frame.Remote || frame.Error {
+		return nil
+	}
+	msg, ok := d.Messages[frame.Key()]
+	if !ok {
+		return nil
+	}
+	mux := -1
+	if multiplexor := msg.Multiplexor(); multiplexor != nil {
+		mux = int(multiplexor.Raw(frame))
+	}
+	var values []Value
+	for _, signal := range msg.Signals {
+		if signal.Multiplexed && signal.MuxValue != mux {
+			continue
+		}
+		values = append(values, Value{Signal: signal, Value: signal.Decode(frame)})
+	}
+	return values
+}
+
+// Decode returns the values of all signals defined for the frame ID keyed by
+// signal name.
+func (d *DBC) Decode(frame Frame) map[string]float64 {
+	kv := map[string]float64{}
+	for _, v := range d.DecodeValues(frame) {
+		kv[v.Signal.Name] = v.Value
+	}
+	return kv
+}
+
+// DBC files set dbcExtendedFlag on 29 bit IDs.
+const (
+	dbcIDMask       = 0x1FFFFFFF
+	dbcExtendedFlag = 0x80000000
+)
+
+var (
+	messageRegexp = regexp.MustCompile(`^BO_\s+(\d+)\s+(\w+)\s*:\s*(\d+)`)
+	signalRegexp  = regexp.MustCompile(`^SG_\s+(\w+)\s*(M|m\d+)?\s*:\s*(\d+)\|(\d+)@([01])([+-])\s*\(([^,]+),([^)]+)\)\s*\[([^|]+)\|([^\]]+)\]\s*"([^"]*)"`)
+	valTypeRegexp = regexp.MustCompile(`^SIG_VALTYPE_\s+(\d+)\s+(\w+)\s*:?\s*([012])\s*;`)
+	commentRegexp = regexp.MustCompile(`(?s)^CM_\s+SG_\s+(\d+)\s+(\w+)\s+"(.*)"\s*;$`)
+	valuesRegexp  = regexp.MustCompile(`^VAL_\s+(\d+)\s+(\w+)((?:\s+-?\d+\s+"[^"]*")*)\s*;`)
+	labelRegexp   = regexp.MustCompile(`(-?\d+)\s+"([^"]*)"`)
+)
+
+// ParseDBC parses the message and signal definitions of a DBC file along with
+// signal comments (CM_ SG_), value tables (VAL_) and value types
+// (SIG_VALTYPE_). Other sections are ignored.
+func ParseDBC(r io.Reader) (*DBC, error) {
+	d := &DBC{Messages: map[MessageKey]*Message{}}
+	var msg *Message
+
+	s := bufio.NewScanner(r)
+	lineNum := 0
+	for s.Scan() {
+		lineNum++
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "CM_") {
+			// Comments may span multiple lines.
+			startLine := lineNum
+			for !strings.HasSuffix(line, ";") && s.Scan() {
+				lineNum++
+				line += "\n" + strings.TrimSpace(s.Text())
+			}
+			if err := d.parseComment(line); err != nil {
+				return nil, errors.Wrapf(err, "line %d", startLine)
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "BO_":
+			matches := messageRegexp.FindStringSubmatch(line)
+			if matches == nil {
+				return nil, errors.Errorf("line %d: invalid message definition %q", lineNum, line)
+			}
+			id, err := strconv.ParseUint(matches[1], 10, 32)
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d", lineNum)
+			}
+			dlc, err := strconv.Atoi(matches[3])
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d", lineNum)
+			}
+			key := dbcMessageKey(id)
+			msg = &Message{
+				ID:       key.ID,
+				Extended: key.Extended,
+				Name:     matches[2],
+				DLC:      dlc,
+			}
+			d.Messages[key] = msg
+
+		case "SG_":
+			if msg == nil {
+				return nil, errors.Errorf("line %d: signal outside of message", lineNum)
+			}
+			signal, err := parseSignal(line)
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d", lineNum)
+			}
+			if signal.Multiplexor && msg.Multiplexor() != nil {
+				return nil, errors.Errorf("line %d: %s has multiple multiplexors", lineNum, msg.Name)
+			}
+			msg.Signals = append(msg.Signals, signal)
+
+		case "SIG_VALTYPE_":
+			if err := d.parseValueType(line); err != nil {
+				return nil, errors.Wrapf(err, "line %d", lineNum)
+			}
+
+		case "VAL_":
+			if err := d.parseValues(line); err != nil {
+				return nil, errors.Wrapf(err, "line %d", lineNum)
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func parseSignal(line string) (*Signal, error) {
+	matches := signalRegexp.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, errors.Errorf("invalid signal definition %q", line)
+	}
+	signal := &Signal{
+		Name:   matches[1],
+		Signed: matches[6] == "-",
+		Unit:   matches[11],
+	}
+	if matches[5] == "0" {
+		signal.ByteOrder = BigEndian
+	}
+
+	var err error
+	switch mux := matches[2]; {
+	case mux == "M":
+		signal.Multiplexor = true
+	case mux != "":
+		signal.Multiplexed = true
+		if signal.MuxValue, err = strconv.Atoi(mux[1:]); err != nil {
+			return nil, err
+		}
+	}
+	if signal.Start, err = strconv.Atoi(matches[3]); err != nil {
+		return nil, err
+	}
+	if signal.Length, err = strconv.Atoi(matches[4]); err != nil {
+		return nil, err
+	}
+	floats := []*float64{&signal.Factor, &signal.Offset, &signal.Min, &signal.Max}
+	for i, f := range floats {
+		if *f, err = strconv.ParseFloat(strings.TrimSpace(matches[7+i]), 64); err != nil {
+			return nil, err
+		}
+	}
+
+	end := signal.Start + signal.Length
+	if signal.ByteOrder == BigEndian {
+		end = bigEndianShift(signal.Start) + signal.Length
+	}
+	if signal.Length <= 0 || signal.Length > 64 || signal.Start < 0 || end > MaxFDDataLen*8 {
+		return nil, errors.Errorf("%s: signal %d|%d does not fit in a frame", signal.Name, signal.Start, signal.Length)
+	}
+	if signal.Factor == 0 {
+		return nil, errors.Errorf("%s: factor must not be 0", signal.Name)
+	}
+	return signal, nil
+}
+
+// dbcMessageKey returns the key of a DBC message ID.
+func dbcMessageKey(id uint64) MessageKey {
+	return MessageKey{
+		ID:       int(id & dbcIDMask),
+		Extended: id&dbcExtendedFlag != 0 || id&dbcIDMask > MaxStandardID,
+	}
+}
+
+// lookupSignal finds the signal referenced by a DBC message ID and signal name.
+func (d *DBC) lookupSignal(id, name string) (*Signal, error) {
+	parsed, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	msg, ok := d.Messages[dbcMessageKey(parsed)]
+	if !ok {
+		return nil, errors.Errorf("unknown message %s", id)
+	}
+	signal := msg.Signal(name)
+	if signal == nil {
+		return nil, errors.Errorf("unknown signal %s", name)
+	}
+	return signal, nil
+}
+
+func (d *DBC) parseComment(line string) error {
+	matches := commentRegexp.FindStringSubmatch(line)
+	if matches == nil {
+		// Only signal comments are used.
+		return nil
+	}
+	signal, err := d.lookupSignal(matches[1], matches[2])
+	if err != nil {
+		return err
+	}
+	signal.Description = matches[3]
+	return nil
+}
+
+func (d *DBC) parseValues(line string) error {
+	matches := valuesRegexp.FindStringSubmatch(line)
+	if matches == nil {
+		return errors.Errorf("invalid value table %q", line)
+	}
+	signal, err := d.lookupSignal(matches[1], matches[2])
+	if err != nil {
+		return err
+	}
+	signal.Values = map[int]string{}
+	for _, label := range labelRegexp.FindAllStringSubmatch(matches[3], -1) {
+		value, err := strconv.Atoi(label[1])
+		if err != nil {
+			return err
+		}
+		signal.Values[value] = label[2]
+	}
+	return nil
+}
+
+func (d *DBC) parseValueType(line string) error {
+	matches := valTypeRegexp.FindStringSubmatch(line)
+	if matches == nil {
+		return errors.Errorf("invalid value type %q", line)
+	}
+	signal, err := d.lookupSignal(matches[1], matches[2])
+	if err != nil {
+		return err
+	}
+
+	switch matches[3] {
+	case "1":
+		signal.ValueType = Float32
+		if signal.Length != 32 {
+			return errors.Errorf("%s: float32 signals must be 32 bits", signal.Name)
+		}
+	case "2":
+		signal.ValueType = Float64
+		if signal.Length != 64 {
+			return errors.Errorf("%s: float64 signals must be 64 bits", signal.Name)
+		}
+	default:
+		signal.ValueType = Integer
+	}
+	return nil
+}
+
+// LoadDBC reads and parses the DBC file at path.
+func LoadDBC(path string) (*DBC, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d, err := ParseDBC(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", path)
+	}
+	return d, nil
+}
+
+func mustParseDBC(s string) *DBC {
+	d, err := ParseDBC(strings.NewReader(s))
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
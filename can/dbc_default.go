@@ -0,0 +1,408 @@
+package can
+
+// DefaultDBC is the built-in signal database used by FrameToKV.
+//
+// Per brick voltages are multiplexed on 0x401. The BMS doesn't broadcast the
+// temperature of each module, 0x332 only carries the hottest and coldest
+// bricks and which modules they're in, so there are no per module
+// temperature signals.
+var DefaultDBC = mustParseDBC(defaultDBC)
+
+const defaultDBC = `VERSION ""
+
+BO_ 264 DIR_torque: 8 Vector__XXX
+ SG_ rear_torque_request_nm : 12|13@1- (0.22222,0) [-910.21312|909.9909] "Nm" Vector__XXX
+ SG_ rear_torque_actual_nm : 27|13@1- (0.22222,0) [-910.21312|909.9909] "Nm" Vector__XXX
+ SG_ rear_axel_rpm : 40|16@1- (0.1,0) [-3276.8|3276.7] "rpm" Vector__XXX
+
+BO_ 280 DI_systemStatus: 8 Vector__XXX
+ SG_ drive_state : 16|3@1+ (1,0) [0|7] "" Vector__XXX
+ SG_ brake_pedal : 19|2@1+ (1,0) [0|3] "" Vector__XXX
+ SG_ gear : 21|3@1+ (1,0) [0|7] "" Vector__XXX
+ SG_ brake_hold : 26|1@1+ (1,0) [0|1] "" Vector__XXX
+ SG_ immobilizer : 27|3@1+ (1,0) [0|7] "" Vector__XXX
+ SG_ pedal_position_pct : 32|8@1+ (0.4,0) [0|102] "%" Vector__XXX
+ SG_ traction_control : 40|3@1+ (1,0) [0|7] "" Vector__XXX
+ SG_ parking_brake : 44|2@1+ (1,0) [0|3] "" Vector__XXX
+ SG_ track_mode : 48|2@1+ (1,0) [0|3] "" Vector__XXX
+
+BO_ 297 SCCM_steeringAngle: 8 Vector__XXX
+ SG_ steering_angle_deg : 16|14@1+ (0.1,-819.2) [-819.2|819.1] "deg" Vector__XXX
+ SG_ steering_speed_dps : 32|14@1+ (0.5,-4096) [-4096|4095.5] "deg/s" Vector__XXX
+
+BO_ 306 BMS_hvBusStatus: 8 Vector__XXX
+ SG_ battery_voltage : 0|16@1+ (0.01,0) [0|655.35] "V" Vector__XXX
+ SG_ battery_current : 16|16@1- (-0.1,0) [-3276.7|3276.8] "A" Vector__XXX
+ SG_ raw_battery_current : 32|16@1- (-0.05,0) [-1638.35|1638.4] "A" Vector__XXX
+ SG_ charge_time_remaining : 48|12@1+ (1,0) [0|4095] "min" Vector__XXX
+
+BO_ 390 DIF_torque: 8 Vector__XXX
+ SG_ front_torque_request_nm : 12|13@1- (0.22222,0) [-910.21312|909.9909] "Nm" Vector__XXX
+ SG_ front_torque_actual_nm : 27|13@1- (0.22222,0) [-910.21312|909.9909] "Nm" Vector__XXX
+ SG_ front_axel_rpm : 40|16@1- (0.1,0) [-3276.8|3276.7] "rpm" Vector__XXX
+
+BO_ 469 DIF_torque2: 8 Vector__XXX
+ SG_ front_torque2_request_nm : 8|15@1- (0.1,0) [-1638.4|1638.3] "Nm" Vector__XXX
+ SG_ front_torque2_nm : 24|13@1- (0.25,0) [-1024|1023.75] "Nm" Vector__XXX
+
+BO_ 472 DIR_torque2: 8 Vector__XXX
+ SG_ rear_torque2_request_nm : 8|15@1- (0.1,0) [-1638.4|1638.3] "Nm" Vector__XXX
+ SG_ rear_torque2_nm : 24|13@1- (0.25,0) [-1024|1023.75] "Nm" Vector__XXX
+
+BO_ 530 BMS_status: 8 Vector__XXX
+ SG_ bms_contactors : 8|3@1+ (1,0) [0|7] "" Vector__XXX
+ SG_ bms_state : 11|4@1+ (1,0) [0|15] "" Vector__XXX
+ SG_ isolation_restance_kohm : 19|10@1+ (1,0) [0|1023] "kOhm" Vector__XXX
+ SG_ bms_charge_status : 32|3@1+ (1,0) [0|7] "" Vector__XXX
+ SG_ bms_charge_power_available_kw : 38|11@1+ (0.125,0) [0|255.875] "kW" Vector__XXX
+ SG_ min_batt_temp_c : 56|8@1+ (0.5,-40) [-40|87.5] "C" Vector__XXX
+
+BO_ 553 SCCM_rightStalk: 8 Vector__XXX
+ SG_ gear_lever_position : 12|3@1+ (1,0) [0|7] "" Vector__XXX
+ SG_ gear_lever_button : 16|2@1+ (1,0) [0|3] "" Vector__XXX
+
+BO_ 577 VCFRONT_coolant: 8 Vector__XXX
+ SG_ battery_coolant_flow_rate_lpm : 0|9@1+ (0.1,0) [0|51.1] "L/min" Vector__XXX
+ SG_ powertrain_coolant_flow_rate : 22|9@1+ (0.1,0) [0|51.1] "L/min" Vector__XXX
+
+BO_ 585 SCCM_leftStalk: 8 Vector__XXX
+ SG_ left_stalk_horizontal : 12|2@1+ (1,0) [0|3] "" Vector__XXX
+ SG_ left_stalk_button : 14|2@1+ (1,0) [0|3] "" Vector__XXX
+ SG_ left_stalk_vertical : 16|3@1+ (1,0) [0|7] "" Vector__XXX
+
+BO_ 594 BMS_powerAvailable: 8 Vector__XXX
+ SG_ regen_power_limit_kw : 0|16@1+ (0.01,0) [0|655.35] "kW" Vector__XXX
+ SG_ discharge_power_limit_kw : 16|16@1+ (0.01,0) [0|655.35] "kW" Vector__XXX
+ SG_ max_heat_parked_kw : 32|10@1+ (0.1,0) [0|102.3] "kW" Vector__XXX
+ SG_ hvac_max_power_kw : 50|10@1+ (0.02,0) [0|20.46] "kW" Vector__XXX
+
+BO_ 599 DI_speed: 8 Vector__XXX
+ SG_ signed_speed : 12|12@1+ (0.05,-25) [-25|179.75] "mph" Vector__XXX
+ SG_ ui_speed : 24|8@1+ (1,0) [0|255] "mph" Vector__XXX
+ SG_ mph_kph_flag : 32|1@1+ (1,0) [0|1] "" Vector__XXX
+
+BO_ 609 VCFRONT_12vBattery: 8 Vector__XXX
+ SG_ 12v_battery_voltage : 0|12@1+ (0.005444,0) [0|22.29318] "V" Vector__XXX
+ SG_ 12v_battery_temp_c : 16|16@1+ (0.01,0) [0|655.35] "C" Vector__XXX
+ SG_ 12v_battery_amp_hours : 32|14@1+ (0.01,0) [0|163.83] "Ah" Vector__XXX
+ SG_ 12v_battery_current_amp : 48|16@1+ (0.005,0) [0|327.675] "A" Vector__XXX
+
+BO_ 612 CHG_lineStatus: 8 Vector__XXX
+ SG_ charge_line_voltage : 0|14@1+ (0.0333,0) [0|545.5539] "V" Vector__XXX
+ SG_ charge_line_current_amp : 14|9@1+ (0.1,0) [0|51.1] "A" Vector__XXX
+ SG_ charge_line_power_kw : 24|8@1+ (0.1,0) [0|25.5] "kW" Vector__XXX
+ SG_ charge_line_current_limit_amp : 32|10@1+ (0.1,0) [0|102.3] "A" Vector__XXX
+
+BO_ 614 DIR_power: 8 Vector__XXX
+ SG_ rear_power_kw : 0|11@1+ (0.5,0) [0|1023.5] "kW" Vector__XXX
+ SG_ rear_heat_power_optimal_kw : 32|8@1+ (0.08,0) [0|20.4] "kW" Vector__XXX
+ SG_ rear_heat_power_max_kw : 40|8@1+ (0.08,0) [0|20.4] "kW" Vector__XXX
+ SG_ rear_heat_power_kw : 48|8@1+ (0.08,0) [0|20.4] "kW" Vector__XXX
+
+BO_ 658 BMS_socStatus: 8 Vector__XXX
+ SG_ ui_state_of_charge_pct : 0|10@1+ (0.1,0) [0|102.3] "%" Vector__XXX
+ SG_ min_state_of_charge_pct : 10|10@1+ (0.1,0) [0|102.3] "%" Vector__XXX
+ SG_ max_state_of_charge_pct : 20|10@1+ (0.1,0) [0|102.3] "%" Vector__XXX
+ SG_ average_state_of_charge_pct : 30|10@1+ (0.1,0) [0|102.3] "%" Vector__XXX
+
+BO_ 741 DIF_power: 8 Vector__XXX
+ SG_ front_power_kw : 0|11@1+ (0.5,0) [0|1023.5] "kW" Vector__XXX
+ SG_ front_heat_power_optimal_kw : 32|8@1+ (0.08,0) [0|20.4] "kW" Vector__XXX
+ SG_ front_heat_power_max_kw : 40|8@1+ (0.08,0) [0|20.4] "kW" Vector__XXX
+ SG_ front_heat_power_kw : 48|8@1+ (0.08,0) [0|20.4] "kW" Vector__XXX
+
+BO_ 659 UI_chassisControl: 8 Vector__XXX
+ SG_ ui_steering_mode : 0|2@1+ (1,0) [0|3] "" Vector__XXX
+ SG_ ui_traction_control_mode : 2|3@1+ (1,0) [0|7] "" Vector__XXX
+
+BO_ 801 VCFRONT_sensors: 8 Vector__XXX
+ SG_ coolant_temp_battery_inlet_c : 0|10@1+ (0.125,-40) [-40|87.875] "C" Vector__XXX
+ SG_ coolant_temp_powertrain_inlet_c : 10|10@1+ (0.125,-40) [-40|87.875] "C" Vector__XXX
+ SG_ ambient_temp_raw_c : 24|8@1+ (0.5,-40) [-40|87.5] "C" Vector__XXX
+ SG_ ambient_temp_filtered_c : 40|8@1+ (0.5,-40) [-40|87.5] "C" Vector__XXX
+
+BO_ 818 BMS_brickMinMax: 8 Vector__XXX
+ SG_ brick_min_max_mux M : 0|2@1+ (1,0) [0|3] "" Vector__XXX
+ SG_ max_brick_temp_c m0 : 16|8@1+ (0.5,-40) [-40|87.5] "C" Vector__XXX
+ SG_ min_brick_temp_c m0 : 24|8@1+ (0.5,-40) [-40|87.5] "C" Vector__XXX
+ SG_ max_brick_temp_num m0 : 2|4@1+ (1,1) [1|16] "" Vector__XXX
+ SG_ min_brick_temp_num m0 : 8|4@1+ (1,1) [1|16] "" Vector__XXX
+ SG_ max_brick_model_temp_c m0 : 32|8@1+ (0.5,-40) [-40|87.5] "C" Vector__XXX
+ SG_ min_brick_model_temp_c m0 : 40|8@1+ (0.5,-40) [-40|87.5] "C" Vector__XXX
+ SG_ max_brick_voltage m1 : 2|12@1+ (0.002,0) [0|8.19] "V" Vector__XXX
+ SG_ min_brick_voltage m1 : 16|12@1+ (0.002,0) [0|8.19] "V" Vector__XXX
+ SG_ max_brick_voltage_num m1 : 32|7@1+ (1,1) [1|128] "" Vector__XXX
+ SG_ min_brick_voltage_num m1 : 40|7@1+ (1,1) [1|128] "" Vector__XXX
+
+BO_ 819 UI_chargeRequest: 8 Vector__XXX
+ SG_ ui_charge_current_limit_amp : 8|7@1+ (1,0) [0|127] "A" Vector__XXX
+ SG_ ui_charge_limit_pct : 16|10@1+ (0.1,0) [0|102.3] "%" Vector__XXX
+
+BO_ 822 DI_powerRating: 8 Vector__XXX
+ SG_ power_rating_kw : 0|9@1+ (1,0) [0|511] "kW" Vector__XXX
+ SG_ regen_rating_kw : 16|8@1+ (1,-100) [-100|155] "kW" Vector__XXX
+
+BO_ 850 BMS_energyStatus: 8 Vector__XXX
+ SG_ full_battery_capacity_kwh : 0|10@1+ (0.1,0) [0|102.3] "kWh" Vector__XXX
+ SG_ remaining_battery_chage_kwh : 10|10@1+ (0.1,0) [0|102.3] "kWh" Vector__XXX
+ SG_ expected_remaining_kwh : 20|10@1+ (0.1,0) [0|102.3] "kWh" Vector__XXX
+ SG_ ideal_remaining_kwh : 30|10@1+ (0.1,0) [0|102.3] "kWh" Vector__XXX
+ SG_ kwh_to_complete_charge : 40|10@1+ (0.1,0) [0|102.3] "kWh" Vector__XXX
+ SG_ energy_buffer_kwh : 50|10@1+ (0.1,0) [0|102.3] "kWh" Vector__XXX
+
+BO_ 886 DI_inverterTemps: 8 Vector__XXX
+ SG_ inverter_pcb_temp_c : 0|8@1+ (1,-40) [-40|215] "C" Vector__XXX
+ SG_ inverter_temp_c : 8|8@1+ (1,-40) [-40|215] "C" Vector__XXX
+ SG_ stator_temp_c : 16|8@1+ (1,-40) [-40|215] "C" Vector__XXX
+ SG_ inverter_capbank_temp_c : 24|8@1+ (1,-40) [-40|215] "C" Vector__XXX
+ SG_ inverter_heatsink_temp_c : 32|8@1+ (1,-40) [-40|215] "C" Vector__XXX
+ SG_ inverter_temp_pct : 40|8@1+ (0.4,0) [0|102] "%" Vector__XXX
+ SG_ stator_temp_pct : 48|8@1+ (0.4,0) [0|102] "%" Vector__XXX
+
+BO_ 918 DIR_oilPump: 8 Vector__XXX
+ SG_ rear_oil_pump_state : 0|3@1+ (1,0) [0|7] "" Vector__XXX
+ SG_ rear_oil_flow_target_lpm : 8|8@1+ (0.06,0) [0|15.3] "L/min" Vector__XXX
+ SG_ rear_oil_flow_actual_lpm : 16|8@1+ (0.06,0) [0|15.3] "L/min" Vector__XXX
+
+BO_ 950 DI_odometer: 8 Vector__XXX
+ SG_ odometer_meter : 0|32@1+ (0.001,0) [0|4294967.295] "m" Vector__XXX
+ SG_ odometer_miles : 0|32@1+ (0.000621371,0) [0|2668768.123] "mi" Vector__XXX
+
+BO_ 978 BMS_kwhCounter: 8 Vector__XXX
+ SG_ total_discharge_kwh : 0|32@1+ (0.001,0) [0|4294967.295] "kWh" Vector__XXX
+ SG_ total_charge_kwh : 32|32@1+ (0.001,0) [0|4294967.295] "kWh" Vector__XXX
+
+BO_ 984 UI_elevation: 8 Vector__XXX
+ SG_ elevation_meter : 0|16@1+ (1,0) [0|65535] "m" Vector__XXX
+
+BO_ 1022 ESP_brakeTemps: 8 Vector__XXX
+ SG_ front_left_brake_temp : 0|10@1+ (1,-40) [-40|983] "C" Vector__XXX
+ SG_ front_right_brake_temp : 10|10@1+ (1,-40) [-40|983] "C" Vector__XXX
+ SG_ rear_left_brake_temp : 20|10@1+ (1,-40) [-40|983] "C" Vector__XXX
+ SG_ rear_right_brake_temp : 30|10@1+ (1,-40) [-40|983] "C" Vector__XXX
+
+BO_ 1025 BMS_brickVoltages: 8 Vector__XXX
+ SG_ brick_voltage_mux M : 0|8@1+ (1,0) [0|31] "" Vector__XXX
+ SG_ brick_voltage_0 m0 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_1 m0 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_2 m0 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_3 m1 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_4 m1 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_5 m1 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_6 m2 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_7 m2 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_8 m2 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_9 m3 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_10 m3 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_11 m3 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_12 m4 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_13 m4 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_14 m4 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_15 m5 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_16 m5 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_17 m5 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_18 m6 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_19 m6 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_20 m6 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_21 m7 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_22 m7 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_23 m7 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_24 m8 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_25 m8 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_26 m8 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_27 m9 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_28 m9 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_29 m9 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_30 m10 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_31 m10 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_32 m10 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_33 m11 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_34 m11 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_35 m11 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_36 m12 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_37 m12 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_38 m12 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_39 m13 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_40 m13 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_41 m13 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_42 m14 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_43 m14 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_44 m14 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_45 m15 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_46 m15 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_47 m15 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_48 m16 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_49 m16 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_50 m16 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_51 m17 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_52 m17 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_53 m17 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_54 m18 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_55 m18 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_56 m18 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_57 m19 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_58 m19 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_59 m19 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_60 m20 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_61 m20 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_62 m20 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_63 m21 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_64 m21 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_65 m21 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_66 m22 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_67 m22 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_68 m22 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_69 m23 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_70 m23 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_71 m23 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_72 m24 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_73 m24 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_74 m24 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_75 m25 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_76 m25 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_77 m25 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_78 m26 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_79 m26 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_80 m26 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_81 m27 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_82 m27 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_83 m27 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_84 m28 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_85 m28 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_86 m28 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_87 m29 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_88 m29 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_89 m29 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_90 m30 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_91 m30 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_92 m30 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_93 m31 : 16|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_94 m31 : 32|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+ SG_ brick_voltage_95 m31 : 48|16@1+ (0.0001,0) [0|6.5535] "V" Vector__XXX
+
+BO_ 1345 FC_limits: 8 Vector__XXX
+ SG_ fast_charge_max_power_limit_kw : 0|13@1+ (0.062256,0) [0|509.938896] "kW" Vector__XXX
+ SG_ fast_charge_max_current_limit_amp : 16|16@1+ (0.073242,0) [0|4799.91447] "A" Vector__XXX
+
+CM_ SG_ 264 rear_torque_request_nm "Torque requested from the rear drive unit.";
+CM_ SG_ 264 rear_torque_actual_nm "Torque produced by the rear drive unit.";
+CM_ SG_ 264 rear_axel_rpm "Rear axle speed.";
+CM_ SG_ 280 drive_state "Drive inverter system state.";
+CM_ SG_ 280 brake_pedal "Brake pedal switch state.";
+CM_ SG_ 280 gear "Selected gear.";
+CM_ SG_ 280 brake_hold "Whether brake hold is active.";
+CM_ SG_ 280 immobilizer "Drive inverter immobilizer state.";
+CM_ SG_ 280 pedal_position_pct "Accelerator pedal position.";
+CM_ SG_ 280 traction_control "Traction control state.";
+CM_ SG_ 280 parking_brake "Parking brake state.";
+CM_ SG_ 280 track_mode "Track mode state.";
+CM_ SG_ 297 steering_angle_deg "Steering wheel angle.";
+CM_ SG_ 297 steering_speed_dps "Steering wheel rotation speed.";
+CM_ SG_ 306 battery_voltage "High voltage battery pack voltage.";
+CM_ SG_ 306 battery_current "Smoothed high voltage battery current, positive when discharging.";
+CM_ SG_ 306 raw_battery_current "Unfiltered high voltage battery current, positive when discharging.";
+CM_ SG_ 306 charge_time_remaining "Estimated time until charging completes.";
+CM_ SG_ 390 front_torque_request_nm "Torque requested from the front drive unit.";
+CM_ SG_ 390 front_torque_actual_nm "Torque produced by the front drive unit.";
+CM_ SG_ 390 front_axel_rpm "Front axle speed.";
+CM_ SG_ 469 front_torque2_request_nm "Front drive unit motor torque request.";
+CM_ SG_ 469 front_torque2_nm "Front drive unit motor torque.";
+CM_ SG_ 472 rear_torque2_request_nm "Rear drive unit motor torque request.";
+CM_ SG_ 472 rear_torque2_nm "Rear drive unit motor torque.";
+CM_ SG_ 530 bms_contactors "High voltage contactor state.";
+CM_ SG_ 530 bms_state "Battery management system state.";
+CM_ SG_ 530 isolation_restance_kohm "High voltage isolation resistance.";
+CM_ SG_ 530 bms_charge_status "Battery management system charge status.";
+CM_ SG_ 530 bms_charge_power_available_kw "Charge power the battery can currently accept.";
+CM_ SG_ 530 min_batt_temp_c "Minimum battery temperature.";
+CM_ SG_ 553 gear_lever_position "Gear stalk position.";
+CM_ SG_ 553 gear_lever_button "Gear stalk park button state.";
+CM_ SG_ 577 battery_coolant_flow_rate_lpm "Battery coolant loop flow rate.";
+CM_ SG_ 577 powertrain_coolant_flow_rate "Powertrain coolant loop flow rate.";
+CM_ SG_ 585 left_stalk_horizontal "Left stalk horizontal position.";
+CM_ SG_ 585 left_stalk_button "Left stalk button state.";
+CM_ SG_ 585 left_stalk_vertical "Left stalk vertical position.";
+CM_ SG_ 594 regen_power_limit_kw "Maximum regenerative braking power the battery accepts.";
+CM_ SG_ 594 discharge_power_limit_kw "Maximum power the battery can deliver.";
+CM_ SG_ 594 max_heat_parked_kw "Maximum battery heating power while parked.";
+CM_ SG_ 594 hvac_max_power_kw "Maximum power available to the HVAC system.";
+CM_ SG_ 599 signed_speed "Vehicle speed, negative in reverse.";
+CM_ SG_ 599 ui_speed "Vehicle speed shown on the display.";
+CM_ SG_ 599 mph_kph_flag "Display speed units, 1 for kph.";
+CM_ SG_ 609 12v_battery_voltage "Low voltage battery voltage.";
+CM_ SG_ 609 12v_battery_temp_c "Low voltage battery temperature.";
+CM_ SG_ 609 12v_battery_amp_hours "Low voltage battery charge.";
+CM_ SG_ 609 12v_battery_current_amp "Low voltage battery current.";
+CM_ SG_ 612 charge_line_voltage "Charger input line voltage.";
+CM_ SG_ 612 charge_line_current_amp "Charger input line current.";
+CM_ SG_ 612 charge_line_power_kw "Charger input line power.";
+CM_ SG_ 612 charge_line_current_limit_amp "Charger input line current limit.";
+CM_ SG_ 614 rear_power_kw "Rear drive unit electrical power.";
+CM_ SG_ 614 rear_heat_power_optimal_kw "Optimal rear drive unit heat generation power.";
+CM_ SG_ 614 rear_heat_power_max_kw "Maximum rear drive unit heat generation power.";
+CM_ SG_ 614 rear_heat_power_kw "Rear drive unit heat generation power.";
+CM_ SG_ 658 ui_state_of_charge_pct "State of charge shown on the display.";
+CM_ SG_ 658 min_state_of_charge_pct "Minimum brick state of charge.";
+CM_ SG_ 658 max_state_of_charge_pct "Maximum brick state of charge.";
+CM_ SG_ 658 average_state_of_charge_pct "Average brick state of charge.";
+CM_ SG_ 741 front_power_kw "Front drive unit electrical power.";
+CM_ SG_ 741 front_heat_power_optimal_kw "Optimal front drive unit heat generation power.";
+CM_ SG_ 741 front_heat_power_max_kw "Maximum front drive unit heat generation power.";
+CM_ SG_ 741 front_heat_power_kw "Front drive unit heat generation power.";
+CM_ SG_ 659 ui_steering_mode "Selected steering mode.";
+CM_ SG_ 659 ui_traction_control_mode "Selected traction control mode.";
+CM_ SG_ 801 coolant_temp_battery_inlet_c "Coolant temperature at the battery inlet.";
+CM_ SG_ 801 coolant_temp_powertrain_inlet_c "Coolant temperature at the powertrain inlet.";
+CM_ SG_ 801 ambient_temp_raw_c "Unfiltered outside air temperature.";
+CM_ SG_ 801 ambient_temp_filtered_c "Filtered outside air temperature.";
+CM_ SG_ 818 brick_min_max_mux "Selects brick temperatures (0) or brick voltages (1).";
+CM_ SG_ 818 max_brick_temp_c "Hottest brick temperature.";
+CM_ SG_ 818 min_brick_temp_c "Coldest brick temperature.";
+CM_ SG_ 818 max_brick_temp_num "Number of the module with the hottest brick.";
+CM_ SG_ 818 min_brick_temp_num "Number of the module with the coldest brick.";
+CM_ SG_ 818 max_brick_model_temp_c "Highest modeled brick temperature.";
+CM_ SG_ 818 min_brick_model_temp_c "Lowest modeled brick temperature.";
+CM_ SG_ 818 max_brick_voltage "Highest brick voltage.";
+CM_ SG_ 818 min_brick_voltage "Lowest brick voltage.";
+CM_ SG_ 818 max_brick_voltage_num "Number of the brick with the highest voltage.";
+CM_ SG_ 818 min_brick_voltage_num "Number of the brick with the lowest voltage.";
+CM_ SG_ 819 ui_charge_current_limit_amp "Charge current limit set in the UI.";
+CM_ SG_ 819 ui_charge_limit_pct "Charge limit set in the UI.";
+CM_ SG_ 822 power_rating_kw "Rated drive power.";
+CM_ SG_ 822 regen_rating_kw "Rated regenerative braking power.";
+CM_ SG_ 850 full_battery_capacity_kwh "Estimated usable battery capacity when full.";
+CM_ SG_ 850 remaining_battery_chage_kwh "Estimated remaining battery energy.";
+CM_ SG_ 850 expected_remaining_kwh "Expected remaining battery energy.";
+CM_ SG_ 850 ideal_remaining_kwh "Ideal remaining battery energy.";
+CM_ SG_ 850 kwh_to_complete_charge "Energy needed to complete charging.";
+CM_ SG_ 850 energy_buffer_kwh "Energy reserved below zero displayed range.";
+CM_ SG_ 886 inverter_pcb_temp_c "Rear inverter circuit board temperature.";
+CM_ SG_ 886 inverter_temp_c "Rear inverter temperature.";
+CM_ SG_ 886 stator_temp_c "Rear motor stator temperature.";
+CM_ SG_ 886 inverter_capbank_temp_c "Rear inverter capacitor bank temperature.";
+CM_ SG_ 886 inverter_heatsink_temp_c "Rear inverter heatsink temperature.";
+CM_ SG_ 886 inverter_temp_pct "Rear inverter temperature relative to its limit.";
+CM_ SG_ 886 stator_temp_pct "Rear motor stator temperature relative to its limit.";
+CM_ SG_ 918 rear_oil_pump_state "Rear drive unit oil pump state.";
+CM_ SG_ 918 rear_oil_flow_target_lpm "Rear drive unit target oil flow.";
+CM_ SG_ 918 rear_oil_flow_actual_lpm "Rear drive unit oil flow.";
+CM_ SG_ 950 odometer_meter "Odometer.";
+CM_ SG_ 950 odometer_miles "Odometer.";
+CM_ SG_ 978 total_discharge_kwh "Lifetime energy discharged from the battery.";
+CM_ SG_ 978 total_charge_kwh "Lifetime energy charged into the battery, including regen.";
+CM_ SG_ 984 elevation_meter "Elevation above sea level.";
+CM_ SG_ 1022 front_left_brake_temp "Estimated front left brake temperature.";
+CM_ SG_ 1022 front_right_brake_temp "Estimated front right brake temperature.";
+CM_ SG_ 1022 rear_left_brake_temp "Estimated rear left brake temperature.";
+CM_ SG_ 1022 rear_right_brake_temp "Estimated rear right brake temperature.";
+CM_ SG_ 1025 brick_voltage_mux "Selects which three brick voltages are present.";
+CM_ SG_ 1345 fast_charge_max_power_limit_kw "Maximum power offered by the fast charger.";
+CM_ SG_ 1345 fast_charge_max_current_limit_amp "Maximum current offered by the fast charger.";
+
+VAL_ 280 drive_state 0 "UNAVAILABLE" 1 "STANDBY" 2 "FAULT" 3 "ABORT" 4 "ENABLE" ;
+VAL_ 280 gear 0 "INVALID" 1 "P" 2 "R" 3 "N" 4 "D" 7 "SNA" ;
+VAL_ 280 immobilizer 0 "INIT_SNA" 1 "REQUEST" 2 "AUTHENTICATING" 3 "DISARMED" 4 "IDLE" 5 "RESET" 6 "FAULT" ;
+VAL_ 530 bms_contactors 0 "SNA" 1 "OPEN" 2 "OPENING" 3 "CLOSING" 4 "CLOSED" 5 "WELDED" 6 "BLOCKED" ;
+VAL_ 530 bms_state 0 "STANDBY" 1 "DRIVE" 2 "SUPPORT" 3 "CHARGE" 4 "FEIM" 5 "CLEAR_FAULT" 6 "FAULT" 7 "WELD" 8 "TEST" 9 "SNA" ;
+VAL_ 659 ui_traction_control_mode 0 "NORMAL" 1 "SLIP_START" 2 "DEV1" 3 "DEV2" 4 "ROLLS_MODE" 5 "DYNO_MODE" 6 "OFFROAD_ASSIST" ;
+`
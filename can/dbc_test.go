@@ -0,0 +1,129 @@
+package can
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDecodeMultiplexed(t *testing.T) {
+	cases := []struct {
+		name  string
+		frame Frame
+		want  map[string]float64
+	}{
+		{
+			name:  "brick temperatures",
+			frame: Frame{ID: 0x332, Data: []byte{0x0C, 0x01, 130, 100, 132, 98, 0, 0}},
+			want: map[string]float64{
+				"brick_min_max_mux":      0,
+				"max_brick_temp_c":       25,
+				"min_brick_temp_c":       10,
+				"max_brick_temp_num":     4,
+				"min_brick_temp_num":     2,
+				"max_brick_model_temp_c": 26,
+				"min_brick_model_temp_c": 9,
+			},
+		},
+		{
+			name:  "brick voltages",
+			frame: Frame{ID: 0x332, Data: []byte{0x41, 0x1F, 0x6C, 0x07, 9, 41, 0, 0}},
+			want: map[string]float64{
+				"brick_min_max_mux":     1,
+				"max_brick_voltage":     4,
+				"min_brick_voltage":     3.8,
+				"max_brick_voltage_num": 10,
+				"min_brick_voltage_num": 42,
+			},
+		},
+		{
+			name:  "unused mux value",
+			frame: Frame{ID: 0x332, Data: []byte{0x03, 0, 0, 0, 0, 0, 0, 0}},
+			want:  map[string]float64{"brick_min_max_mux": 3},
+		},
+		{
+			name:  "per brick voltages",
+			frame: Frame{ID: 0x401, Data: []byte{2, 0, 0x40, 0x9C, 0x58, 0x98, 0x28, 0xA0}},
+			want: map[string]float64{
+				"brick_voltage_mux": 2,
+				"brick_voltage_6":   4,
+				"brick_voltage_7":   3.9,
+				"brick_voltage_8":   4.1,
+			},
+		},
+	}
+	for _, c := range cases {
+		out := DefaultDBC.Decode(c.frame)
+		if len(out) != len(c.want) {
+			t.Errorf("%s: Decode = %v; expected %v", c.name, out, c.want)
+			continue
+		}
+		for key, want := range c.want {
+			if got, ok := out[key]; !ok || math.Abs(got-want) > 1e-9 {
+				t.Errorf("%s: %s = %g, %t; expected %g", c.name, key, got, ok, want)
+			}
+		}
+	}
+}
+
+func TestMuxGroup(t *testing.T) {
+	cases := []struct {
+		name  string
+		group string
+		index int
+		ok    bool
+	}{
+		{"brick_voltage_42", "brick_voltage", 42, true},
+		{"max_brick_temp_c", "", 0, false},
+		{"brick_voltage_mux", "", 0, false},
+		{"battery_voltage", "", 0, false},
+	}
+	for _, c := range cases {
+		signal := DefaultDBC.Signal(c.name)
+		if signal == nil {
+			t.Fatalf("unknown signal %q", c.name)
+		}
+		group, index, ok := signal.MuxGroup()
+		if group != c.group || index != c.index || ok != c.ok {
+			t.Errorf("%s.MuxGroup() = %q, %d, %t; expected %q, %d, %t", c.name, group, index, ok, c.group, c.index, c.ok)
+		}
+	}
+}
+
+func TestDecodeExtended(t *testing.T) {
+	// 0x80000100 is the DBC encoding of the extended ID 0x100.
+	db, err := ParseDBC(strings.NewReader(`VERSION ""
+
+BO_ 256 standard: 8 Vector__XXX
+ SG_ a : 0|8@1+ (1,0) [0|255] "" Vector__XXX
+
+BO_ 2147483904 extended: 8 Vector__XXX
+ SG_ b : 0|8@1+ (2,0) [0|510] "" Vector__XXX
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.Messages) != 2 {
+		t.Fatalf("parsed %d messages; expected 2", len(db.Messages))
+	}
+	cases := []struct {
+		frame Frame
+		want  map[string]float64
+	}{
+		{Frame{ID: 0x100, Data: []byte{3}}, map[string]float64{"a": 3}},
+		{Frame{ID: 0x100, Extended: true, Data: []byte{3}}, map[string]float64{"b": 6}},
+		{Frame{ID: 0x101, Extended: true, Data: []byte{3}}, map[string]float64{}},
+	}
+	for _, c := range cases {
+		out := db.Decode(c.frame)
+		if len(out) != len(c.want) {
+			t.Errorf("Decode(%v) = %v; expected %v", c.frame, out, c.want)
+			continue
+		}
+		for key, want := range c.want {
+			if got := out[key]; got != want {
+				t.Errorf("Decode(%v) %s = %g; expected %g", c.frame, key, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+//go:build go1.18
+// +build go1.18
+
+package can
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// The fuzz targets run their seed corpus as part of go test. Fuzz them with:
+//
+//	go test ./can -fuzz FuzzParseCSV
+
+// FuzzParseCSV checks that ParseCSV doesn't panic and that any frame it
+// accepts survives a round trip through FormatCSV.
+func FuzzParseCSV(f *testing.F) {
+	seed, err := os.ReadFile(filepath.Join("testdata", "frames.csv"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(seed)
+	f.Add([]byte("305419896,0,0,0,0,0,0,0,255,0\n"))
+	f.Add([]byte("1,1,2,3,4,5,6,7,8,9,10,11,12,5\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return
+		}
+		for _, row := range rows {
+			frame, err := ParseCSV(row)
+			if err != nil {
+				continue
+			}
+			out, err := ParseCSV(FormatCSV(frame))
+			if err != nil {
+				t.Fatalf("ParseCSV(FormatCSV(%+v)): %v", frame, err)
+			}
+			if !reflect.DeepEqual(out, frame) {
+				t.Fatalf("ParseCSV(FormatCSV(%+v)) = %+v", frame, out)
+			}
+		}
+	})
+}
+
+// FuzzReadBits checks the bit readers against a bit by bit implementation.
+// n and start select the field and data is the frame data, up to 64 bytes.
+func FuzzReadBits(f *testing.F) {
+	f.Add(uint8(16), uint8(8), []byte{0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0})
+	f.Add(uint8(12), uint8(67), []byte{0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0, 0x0F, 0xFF})
+	f.Add(uint8(64), uint8(0), bytes.Repeat([]byte{0xA5}, 64))
+	f.Fuzz(func(t *testing.T, nb, startb uint8, data []byte) {
+		if len(data) == 0 {
+			return
+		}
+		if len(data) > MaxFDDataLen {
+			data = data[:MaxFDDataLen]
+		}
+		frame := Frame{Data: data}
+		size := 8 * len(frame.Data)
+		n := int(nb)%64 + 1
+		if n > size {
+			n = size
+		}
+
+		start := int(startb) % (size - n + 1)
+		want := naiveBits(frame, n, start, LittleEndian)
+		if got := uint64(frame.ReadBits(n, start)); got != want {
+			t.Fatalf("%v.ReadBits(%d, %d) = %#x; expected %#x", frame, n, start, got, want)
+		}
+		if got := frame.ReadSignedBits(n, start); got != signExtend(want, n) {
+			t.Fatalf("%v.ReadSignedBits(%d, %d) = %d; expected %d", frame, n, start, got, signExtend(want, n))
+		}
+
+		// Only check big endian start bits whose field fits within the frame.
+		start = int(startb) % size
+		if bigEndianShift(start)+n > size {
+			return
+		}
+		want = naiveBits(frame, n, start, BigEndian)
+		if got := uint64(frame.ReadBitsBigEndian(n, start)); got != want {
+			t.Fatalf("%v.ReadBitsBigEndian(%d, %d) = %#x; expected %#x", frame, n, start, got, want)
+		}
+		if got := frame.ReadSignedBitsBigEndian(n, start); got != signExtend(want, n) {
+			t.Fatalf("%v.ReadSignedBitsBigEndian(%d, %d) = %d; expected %d", frame, n, start, got, signExtend(want, n))
+		}
+	})
+}
+
+// naiveBits reads a field one bit at a time following the DBC bit numbering.
+func naiveBits(frame Frame, n, start int, order ByteOrder) uint64 {
+	bit := func(pos int) uint64 {
+		return uint64(frame.Data[pos/8]>>uint(pos%8)) & 1
+	}
+	var v uint64
+	if order == LittleEndian {
+		for i := 0; i < n; i++ {
+			v |= bit(start+i) << uint(i)
+		}
+		return v
+	}
+	pos := start
+	for i := 0; i < n; i++ {
+		v = v<<1 | bit(pos)
+		if pos%8 == 0 {
+			pos += 15
+		} else {
+			pos--
+		}
+	}
+	return v
+}
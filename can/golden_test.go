@@ -0,0 +1,174 @@
+package can
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testdata/frames.golden is the output of FrameToKV for testdata/frames.csv
+// from the original hand written decoder, before signals were decoded from
+// the DBC. Each frame is a "# 0xID data" line followed by "key = value" lines.
+
+// goldenFrame is a frame and its decoded values from a golden file.
+type goldenFrame struct {
+	header string
+	values map[string]float64
+}
+
+func readGolden(t *testing.T, path string) []goldenFrame {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var frames []goldenFrame
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			frames = append(frames, goldenFrame{header: line, values: map[string]float64{}})
+			continue
+		}
+		parts := strings.SplitN(line, " = ", 2)
+		if len(parts) != 2 || len(frames) == 0 {
+			t.Fatalf("invalid golden line %q", line)
+		}
+		value, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			t.Fatalf("invalid golden line %q: %v", line, err)
+		}
+		frames[len(frames)-1].values[parts[0]] = value
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return frames
+}
+
+// resignedSignal is how the original decoder read a signal that's now signed.
+// It read them as unsigned and some faked the sign with a negative scale and
+// an offset.
+type resignedSignal struct {
+	bits          int
+	scale, offset float64
+}
+
+// resignedSignals are decoded as two's complement since signed decoding was
+// added. Their golden values are checked by recovering the raw field and
+// decoding it with the current signal definition.
+var resignedSignals = map[string]resignedSignal{
+	"rear_torque_request_nm":   {13, 0.22222, 0},
+	"rear_torque_actual_nm":    {13, 0.22222, 0},
+	"rear_axel_rpm":            {16, 0.1, 0},
+	"front_torque_request_nm":  {13, 0.22222, 0},
+	"front_torque_actual_nm":   {13, 0.22222, 0},
+	"front_axel_rpm":           {16, 0.1, 0},
+	"front_torque2_request_nm": {15, 0.1, 0},
+	"front_torque2_nm":         {13, 0.25, 0},
+	"rear_torque2_request_nm":  {15, 0.1, 0},
+	"rear_torque2_nm":          {13, 0.25, 0},
+	"raw_battery_current":      {16, -0.05, 1000},
+}
+
+// replacedSignals were decoded wrongly by the original decoder so their golden
+// values aren't checked, see the tests named in each comment instead.
+var replacedSignals = map[string]bool{
+	// TestBatteryCurrent
+	"battery_current": true,
+}
+
+// expectedValue converts a golden value for key to the value expected from
+// the current decoder.
+func expectedValue(t *testing.T, key string, golden float64) float64 {
+	r, ok := resignedSignals[key]
+	if !ok {
+		return golden
+	}
+	signal := DefaultDBC.Signal(key)
+	if signal == nil {
+		t.Fatalf("unknown signal %q", key)
+	}
+	raw := uint64(math.Round((golden - r.offset) / r.scale))
+	return float64(signExtend(raw, r.bits))*signal.Factor + signal.Offset
+}
+
+func TestFrameToKVGolden(t *testing.T) {
+	input := filepath.Join("testdata", "frames.csv")
+	golden := readGolden(t, filepath.Join("testdata", "frames.golden"))
+
+	f, err := os.Open(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(golden) {
+		t.Fatalf("%s has %d frames; golden has %d", input, len(rows), len(golden))
+	}
+
+	for i, row := range rows {
+		frame, err := ParseCSV(row)
+		if err != nil {
+			t.Fatalf("%s:%d: %v", input, i+1, err)
+		}
+		want := golden[i]
+		if header := fmt.Sprintf("# 0x%03X % X", frame.ID, frame.Data); header != want.header {
+			t.Fatalf("%s:%d: frame %q; golden has %q", input, i+1, header, want.header)
+		}
+		// Signals added since the golden file was written are ignored.
+		kv := FrameToKV(frame)
+		for key, golden := range want.values {
+			if replacedSignals[key] {
+				continue
+			}
+			got, ok := kv[key]
+			if !ok {
+				t.Errorf("%s: %s not decoded", want.header, key)
+				continue
+			}
+			expected := expectedValue(t, key, golden)
+			if tolerance := 1e-9 * math.Max(1, math.Abs(expected)); math.Abs(got-expected) > tolerance {
+				t.Errorf("%s: %s = %g; expected %g", want.header, key, got, expected)
+			}
+		}
+	}
+}
+
+// TestBatteryCurrent checks the smoothed battery current, which is a signed
+// field of 0.1A. The original decoder read it as 1000 - 0.01 * the unsigned
+// field, which can't go below 344.65A so it couldn't show an idle or charging
+// car, and disagrees with the current definition for every frame.
+func TestBatteryCurrent(t *testing.T) {
+	cases := []struct {
+		name  string
+		frame Frame
+		want  float64
+	}{
+		// The original decoder gave 1000A.
+		{"idle", Frame{ID: 0x132, Data: []byte{0x11, 0xCA, 0x00, 0x00, 0, 0, 0, 0}}, 0},
+		// The original decoder gave 354.64A.
+		{"discharging", Frame{ID: 0x132, Data: []byte{0x11, 0xCA, 0x18, 0xFC, 0, 0, 0, 0}}, 100},
+		// The original decoder gave 990A.
+		{"charging", Frame{ID: 0x132, Data: []byte{0x11, 0xCA, 0xE8, 0x03, 0, 0, 0, 0}}, -100},
+		// The 0x132 frame in testdata/frames.golden, where the original
+		// decoder gave 372.63A.
+		{"golden", Frame{ID: 0x132, Data: []byte{0xFF, 0xC9, 0x11, 0xF5, 0x7C, 0xCE, 0xD4, 0x58}}, 279.9},
+	}
+	for _, c := range cases {
+		got, ok := FrameToKV(c.frame)["battery_current"]
+		if !ok || math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%s: battery_current = %g, %t; expected %g", c.name, got, ok, c.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package can
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var gvretHeader = []string{"Time Stamp", "ID", "Extended", "Dir", "Bus", "LEN", "D1", "D2", "D3", "D4", "D5", "D6", "D7", "D8"}
+
+// GVRETReader reads SavvyCAN/GVRET CSV logs where the time stamp is in
+// microseconds.
+type GVRETReader struct {
+	r   *csv.Reader
+	row int
+}
+
+func NewGVRETReader(r io.Reader) *GVRETReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	return &GVRETReader{r: reader}
+}
+
+func (r *GVRETReader) Read() (Record, error) {
+	for {
+		row, err := r.r.Read()
+		if err != nil {
+			return Record{}, err
+		}
+		r.row++
+		if len(row) > 0 && row[0] == gvretHeader[0] {
+			continue
+		}
+		record, err := parseGVRETRow(row)
+		if err != nil {
+			return Record{}, errors.Wrapf(err, "row %d", r.row)
+		}
+		return record, nil
+	}
+}
+
+func parseGVRETRow(row []string) (Record, error) {
+	if len(row) < 6 {
+		return Record{}, errors.Errorf("expected at least 6 columns, got %d", len(row))
+	}
+	micros, err := strconv.ParseInt(row[0], 10, 64)
+	if err != nil {
+		return Record{}, err
+	}
+	id, err := strconv.ParseUint(row[1], 16, 29)
+	if err != nil {
+		return Record{}, err
+	}
+	extended, err := strconv.ParseBool(row[2])
+	if err != nil {
+		return Record{}, err
+	}
+	n, err := strconv.Atoi(row[5])
+	if err != nil {
+		return Record{}, err
+	}
+	if !ValidDataLen(n, true) {
+		return Record{}, errors.Errorf("invalid LEN %d", n)
+	}
+	if n > len(row)-6 {
+		return Record{}, errors.Errorf("LEN %d but only %d data columns", n, len(row)-6)
+	}
+
+	frame := Frame{
+		ID:       int(id),
+		Extended: extended || id > MaxStandardID,
+		FD:       n > MaxDataLen,
+		Data:     make([]byte, n),
+	}
+	for i, b := range row[6 : 6+n] {
+		parsed, err := strconv.ParseUint(b, 16, 8)
+		if err != nil {
+			return Record{}, err
+		}
+		frame.Data[i] = byte(parsed)
+	}
+	return Record{
+		Frame: frame,
+		Time:  time.Unix(0, micros*int64(time.Microsecond)),
+	}, nil
+}
+
+// GVRETWriter writes SavvyCAN/GVRET CSV logs. The format can't represent
+// remote or error frames so they are skipped.
+type GVRETWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func NewGVRETWriter(w io.Writer) *GVRETWriter {
+	return &GVRETWriter{w: csv.NewWriter(w)}
+}
+
+func (w *GVRETWriter) Write(record Record) error {
+	if record.Frame.Remote || record.Frame.Error {
+		return nil
+	}
+	if !w.wroteHeader {
+		if err := w.w.Write(gvretHeader); err != nil {
+			return err
+		}
+		w.wroteHeader = true
+	}
+	row := []string{
+		strconv.FormatInt(record.Time.UnixNano()/int64(time.Microsecond), 10),
+		fmt.Sprintf("%08X", record.Frame.ID),
+		strconv.FormatBool(record.Frame.Extended),
+		"Rx",
+		"0",
+		strconv.Itoa(len(record.Frame.Data)),
+	}
+	for _, b := range record.Frame.Data {
+		row = append(row, fmt.Sprintf("%02X", b))
+	}
+	return w.w.Write(row)
+}
+
+func (w *GVRETWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
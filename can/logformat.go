@@ -0,0 +1,183 @@
+package can
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// RecordReader reads a sequence of records, returning io.EOF at the end.
+type RecordReader interface {
+	Read() (Record, error)
+}
+
+// RecordWriter writes a sequence of records. Close flushes any buffered
+// records and writes trailers but doesn't close the underlying writer.
+type RecordWriter interface {
+	Write(Record) error
+	Close() error
+}
+
+// Format is a CAN log file format.
+type Format string
+
+const (
+	// FormatJSON is one JSON encoded Record per line.
+	FormatJSON Format = "json"
+	// FormatCandump is the candump -L log format from can-utils.
+	FormatCandump Format = "candump"
+	// FormatASC is the Vector ASCII log format.
+	FormatASC Format = "asc"
+	// FormatGVRET is the SavvyCAN/GVRET CSV format.
+	FormatGVRET Format = "gvret"
+	// FormatBinary is the compact block compressed binary format.
+	FormatBinary Format = "binary"
+)
+
+// Formats lists all supported log formats.
+var Formats = []Format{FormatJSON, FormatCandump, FormatASC, FormatGVRET, FormatBinary}
+
+// NewRecordReader returns a reader for logs in the given format.
+func NewRecordReader(format Format, r io.Reader) (RecordReader, error) {
+	switch format {
+	case FormatJSON:
+		return NewJSONReader(r), nil
+	case FormatCandump:
+		return NewCandumpReader(r), nil
+	case FormatASC:
+		return NewASCReader(r), nil
+	case FormatGVRET:
+		return NewGVRETReader(r), nil
+	case FormatBinary:
+		return NewBinaryReader(r), nil
+	default:
+		return nil, errors.Errorf("unknown log format %q", format)
+	}
+}
+
+// NewRecordWriter returns a writer for logs in the given format.
+func NewRecordWriter(format Format, w io.Writer) (RecordWriter, error) {
+	switch format {
+	case FormatJSON:
+		return NewJSONWriter(w), nil
+	case FormatCandump:
+		return NewCandumpWriter(w), nil
+	case FormatASC:
+		return NewASCWriter(w), nil
+	case FormatGVRET:
+		return NewGVRETWriter(w), nil
+	case FormatBinary:
+		return NewBinaryWriter(w), nil
+	default:
+		return nil, errors.Errorf("unknown log format %q", format)
+	}
+}
+
+// DetectFormat guesses the format of a log from its first bytes without
+// consuming them.
+func DetectFormat(r *bufio.Reader) (Format, error) {
+	head, err := r.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", err
+	}
+	if bytes.HasPrefix(head, []byte(binaryMagic)) {
+		return FormatBinary, nil
+	}
+	head = bytes.TrimLeft(head, " \t\r\n")
+	switch {
+	case bytes.HasPrefix(head, []byte("{")):
+		return FormatJSON, nil
+	case bytes.HasPrefix(head, []byte("(")):
+		return FormatCandump, nil
+	case bytes.HasPrefix(head, []byte("Time Stamp,")):
+		return FormatGVRET, nil
+	case bytes.HasPrefix(head, []byte("date ")),
+		bytes.Contains(head, []byte("Begin Triggerblock")),
+		bytes.Contains(head, []byte("base hex")),
+		bytes.Contains(head, []byte("base dec")):
+		return FormatASC, nil
+	}
+	return "", errors.New("unable to detect log format")
+}
+
+// OpenRecordReader detects the format of r and returns a reader for it.
+func OpenRecordReader(r io.Reader) (RecordReader, Format, error) {
+	br := bufio.NewReader(r)
+	format, err := DetectFormat(br)
+	if err != nil {
+		return nil, "", err
+	}
+	reader, err := NewRecordReader(format, br)
+	if err != nil {
+		return nil, "", err
+	}
+	return reader, format, nil
+}
+
+// ReadAll reads records until io.EOF.
+func ReadAll(r RecordReader) ([]Record, error) {
+	var records []Record
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+// JSONReader reads one JSON encoded Record per line.
+type JSONReader struct {
+	s *bufio.Scanner
+}
+
+func NewJSONReader(r io.Reader) *JSONReader {
+	return &JSONReader{s: bufio.NewScanner(r)}
+}
+
+func (r *JSONReader) Read() (Record, error) {
+	for r.s.Scan() {
+		line := bytes.TrimSpace(r.s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			return Record{}, err
+		}
+		return record, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+// JSONWriter writes one JSON encoded Record per line.
+type JSONWriter struct {
+	w *bufio.Writer
+}
+
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *JSONWriter) Write(record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(body); err != nil {
+		return err
+	}
+	return w.w.WriteByte('\n')
+}
+
+func (w *JSONWriter) Close() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,98 @@
+package can
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func roundTrip(t *testing.T, format Format, records []Record) []Record {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewRecordWriter(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, detected, err := OpenRecordReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detected != format {
+		t.Errorf("detected %q; expected %q", detected, format)
+	}
+	out, err := ReadAll(r)
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", format, err, buf.String())
+	}
+	return out
+}
+
+func checkRoundTrip(t *testing.T, format Format, got, want []Record) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: read %d records; expected %d", format, len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) || got[i].Bus != want[i].Bus || !reflect.DeepEqual(got[i].Frame, want[i].Frame) {
+			t.Errorf("%s: %d: read %+v %s; expected %+v %s", format, i, got[i], got[i].Time, want[i], want[i].Time)
+		}
+	}
+}
+
+// The timestamps have sub-millisecond parts and are in a zone other than UTC
+// to catch precision and time zone handling bugs.
+var logStart = time.Date(2021, 3, 4, 5, 6, 7, 126456000, time.FixedZone("PST", -8*60*60))
+
+func TestCandumpRoundTrip(t *testing.T) {
+	records := []Record{
+		{Frame: Frame{ID: 0x118, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}}, Bus: "can0"},
+		{Frame: Frame{ID: 0x12345678, Extended: true, Data: []byte{0xFF}}, Bus: "can1"},
+		{Frame: Frame{ID: 0x1, Remote: true, Data: make([]byte, 4)}, Bus: "can0"},
+		{Frame: Frame{ID: 0x3D2, FD: true, Data: bytes.Repeat([]byte{0xAB}, 12)}, Bus: "can0"},
+		{Frame: Frame{ID: 0x4, Error: true, Data: make([]byte, 8)}, Bus: "can0"},
+	}
+	for i := range records {
+		records[i].Time = logStart.Add(time.Duration(i) * 1500 * time.Microsecond)
+	}
+	checkRoundTrip(t, FormatCandump, roundTrip(t, FormatCandump, records), records)
+}
+
+func TestASCRoundTrip(t *testing.T) {
+	records := []Record{
+		{Frame: Frame{ID: 0x118, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}}},
+		{Frame: Frame{ID: 0x12345678, Extended: true, Data: []byte{0xFF}}},
+		{Frame: Frame{ID: 0x1, Remote: true, Data: make([]byte, 4)}},
+		{Frame: Frame{ID: 0x3D2, FD: true, Data: bytes.Repeat([]byte{0xAB}, 12)}},
+		{Frame: Frame{Error: true}},
+	}
+	for i := range records {
+		records[i].Time = logStart.Add(time.Duration(i) * 1500 * time.Microsecond)
+	}
+	checkRoundTrip(t, FormatASC, roundTrip(t, FormatASC, records), records)
+}
+
+func TestGVRETRoundTrip(t *testing.T) {
+	records := []Record{
+		{Frame: Frame{ID: 0x118, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}}},
+		{Frame: Frame{ID: 0x12345678, Extended: true, Data: []byte{0xFF}}},
+		{Frame: Frame{ID: 0x3D2, FD: true, Data: bytes.Repeat([]byte{0xAB}, 12)}},
+	}
+	for i := range records {
+		records[i].Time = logStart.Add(time.Duration(i) * 1500 * time.Microsecond)
+	}
+	skipped := []Record{
+		{Frame: Frame{ID: 0x1, Remote: true, Data: make([]byte, 4)}, Time: logStart},
+		{Frame: Frame{ID: 0x4, Error: true, Data: make([]byte, 8)}, Time: logStart},
+	}
+	got := roundTrip(t, FormatGVRET, append(append([]Record(nil), records...), skipped...))
+	checkRoundTrip(t, FormatGVRET, got, records)
+}
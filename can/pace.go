@@ -0,0 +1,51 @@
+package can
+
+import (
+	"context"
+	"time"
+)
+
+// PacedReader replays records at the rate they were recorded, scaled by
+// Speed. A Speed of 2 replays twice as fast and 0 doesn't wait at all. Waits
+// end early with the context's error once it's done.
+type PacedReader struct {
+	ctx   context.Context
+	r     RecordReader
+	Speed float64
+
+	started   bool
+	wallStart time.Time
+	logStart  time.Time
+}
+
+func NewPacedReader(ctx context.Context, r RecordReader, speed float64) *PacedReader {
+	return &PacedReader{ctx: ctx, r: r, Speed: speed}
+}
+
+// Read returns the next record once it's due.
+func (p *PacedReader) Read() (Record, error) {
+	record, err := p.r.Read()
+	if err != nil {
+		return Record{}, err
+	}
+	if p.Speed <= 0 {
+		return record, nil
+	}
+	if !p.started {
+		p.started = true
+		p.wallStart = time.Now()
+		p.logStart = record.Time
+		return record, nil
+	}
+	offset := time.Duration(float64(record.Time.Sub(p.logStart)) / p.Speed)
+	if wait := time.Until(p.wallStart.Add(offset)); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-p.ctx.Done():
+			return Record{}, p.ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return record, nil
+}
@@ -0,0 +1,178 @@
+//go:build linux
+// +build linux
+
+package can
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// canFrameSize is the size of struct can_frame.
+	canFrameSize = 16
+	// canFDFrameSize is the size of struct canfd_frame.
+	canFDFrameSize = 72
+
+	canEFFFlag = 0x80000000
+	canRTRFlag = 0x40000000
+	canErrFlag = 0x20000000
+	canEFFMask = 0x1FFFFFFF
+)
+
+// SocketCAN reads frames from a Linux SocketCAN interface. Classic, CAN FD and
+// error frames are received.
+type SocketCAN struct {
+	f    *os.File
+	conn syscall.RawConn
+}
+
+// OpenSocketCAN opens a raw CAN socket bound to iface with kernel receive
+// timestamps enabled.
+func OpenSocketCAN(iface string) (*SocketCAN, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, errors.Wrapf(err, "socketcan %s", iface)
+	}
+	fd, err := unix.Socket(unix.AF_CAN, unix.SOCK_RAW|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, unix.CAN_RAW)
+	if err != nil {
+		return nil, errors.Wrap(err, "socket")
+	}
+	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_TIMESTAMPNS, 1); err != nil {
+		unix.Close(fd)
+		return nil, errors.Wrap(err, "SO_TIMESTAMPNS")
+	}
+	// Interfaces that don't support CAN FD reject the option but still
+	// work for classic frames.
+	_ = unix.SetsockoptInt(fd, unix.SOL_CAN_RAW, unix.CAN_RAW_FD_FRAMES, 1)
+	if err := unix.SetsockoptInt(fd, unix.SOL_CAN_RAW, unix.CAN_RAW_ERR_FILTER, unix.CAN_ERR_MASK); err != nil {
+		unix.Close(fd)
+		return nil, errors.Wrap(err, "CAN_RAW_ERR_FILTER")
+	}
+	if err := unix.Bind(fd, &unix.SockaddrCAN{Ifindex: ifi.Index}); err != nil {
+		unix.Close(fd)
+		return nil, errors.Wrapf(err, "bind %s", iface)
+	}
+
+	// Wrapping the non-blocking socket in a File registers it with the
+	// runtime poller so Close unblocks pending reads.
+	f := os.NewFile(uintptr(fd), "socketcan:"+iface)
+	conn, err := f.SyscallConn()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &SocketCAN{f: f, conn: conn}, nil
+}
+
+// Read returns the next frame, timestamped with the time the kernel received
+// it.
+func (s *SocketCAN) Read() (Record, error) {
+	buf := make([]byte, canFDFrameSize)
+	oob := make([]byte, unix.CmsgSpace(int(unsafe.Sizeof(unix.Timespec{}))))
+
+	var n, oobn int
+	var recvErr error
+	if err := s.conn.Read(func(fd uintptr) bool {
+		n, oobn, _, _, recvErr = unix.Recvmsg(int(fd), buf, oob, 0)
+		return recvErr != unix.EAGAIN
+	}); err != nil {
+		return Record{}, err
+	}
+	if recvErr != nil {
+		return Record{}, errors.Wrap(recvErr, "recvmsg")
+	}
+	if n != canFrameSize && n != canFDFrameSize {
+		return Record{}, errors.Errorf("invalid socketcan frame: %d bytes", n)
+	}
+	frame := parseSocketCANFrame(buf[:n])
+
+	record := Record{
+		Frame: frame,
+		Time:  time.Now(),
+	}
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return Record{}, err
+	}
+	for _, msg := range msgs {
+		if msg.Header.Level == unix.SOL_SOCKET && msg.Header.Type == unix.SCM_TIMESTAMPNS {
+			ts := *(*unix.Timespec)(unsafe.Pointer(&msg.Data[0]))
+			record.Time = time.Unix(ts.Unix())
+		}
+	}
+	return record, nil
+}
+
+// parseSocketCANFrame parses a struct can_frame or, if buf is
+// canFDFrameSize bytes, a struct canfd_frame.
+func parseSocketCANFrame(buf []byte) Frame {
+	// SocketCAN uses host byte order, all supported hosts are little
+	// endian.
+	id := binary.LittleEndian.Uint32(buf)
+	frame := Frame{
+		ID:       int(id & canEFFMask),
+		Extended: id&canEFFFlag != 0,
+		Remote:   id&canRTRFlag != 0,
+		Error:    id&canErrFlag != 0,
+		FD:       len(buf) == canFDFrameSize,
+	}
+	n := int(buf[4])
+	max := MaxDataLen
+	if frame.FD {
+		max = MaxFDDataLen
+	}
+	if n > max {
+		n = max
+	}
+	frame.Data = make([]byte, n)
+	if !frame.Remote {
+		copy(frame.Data, buf[8:8+n])
+	}
+	return frame
+}
+
+// Write sends a frame on the bus. CAN FD frames require an interface with
+// CAN FD enabled.
+func (s *SocketCAN) Write(frame Frame) error {
+	size := canFrameSize
+	if frame.FD {
+		size = canFDFrameSize
+	}
+	if len(frame.Data) > size-8 {
+		return errors.Errorf("frame data too long: %d bytes", len(frame.Data))
+	}
+	buf := make([]byte, size)
+	id := uint32(frame.ID) & canEFFMask
+	if frame.Extended || id > MaxStandardID {
+		id |= canEFFFlag
+	}
+	if frame.Remote {
+		id |= canRTRFlag
+	}
+	binary.LittleEndian.PutUint32(buf, id)
+	buf[4] = byte(len(frame.Data))
+	if !frame.Remote {
+		copy(buf[8:], frame.Data)
+	}
+
+	var writeErr error
+	if err := s.conn.Write(func(fd uintptr) bool {
+		_, writeErr = unix.Write(int(fd), buf)
+		return writeErr != unix.EAGAIN
+	}); err != nil {
+		return err
+	}
+	return errors.Wrap(writeErr, "write")
+}
+
+func (s *SocketCAN) Close() error {
+	return s.f.Close()
+}
@@ -0,0 +1,62 @@
+//go:build linux
+// +build linux
+
+package can
+
+import (
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// testInterface is a virtual CAN interface, create it with:
+//
+//	ip link add dev vcan0 type vcan && ip link set up vcan0
+const testInterface = "vcan0"
+
+func TestSocketCANRead(t *testing.T) {
+	ifi, err := net.InterfaceByName(testInterface)
+	if err != nil {
+		t.Skipf("%s not available: %v", testInterface, err)
+	}
+
+	s, err := OpenSocketCAN(testInterface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	fd, err := unix.Socket(unix.AF_CAN, unix.SOCK_RAW, unix.CAN_RAW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(fd)
+	if err := unix.Bind(fd, &unix.SockaddrCAN{Ifindex: ifi.Index}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Frame{ID: 0x118, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}}
+	buf := make([]byte, canFrameSize)
+	binary.LittleEndian.PutUint32(buf, uint32(want.ID))
+	buf[4] = byte(len(want.Data))
+	copy(buf[8:], want.Data)
+	before := time.Now()
+	if _, err := unix.Write(fd, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(record.Frame, want) {
+		t.Errorf("Read() = %+v; expected %+v", record.Frame, want)
+	}
+	if record.Time.Before(before.Add(-time.Second)) || record.Time.After(time.Now()) {
+		t.Errorf("Read() time = %s; expected kernel receive time", record.Time)
+	}
+}
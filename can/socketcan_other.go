@@ -0,0 +1,26 @@
+//go:build !linux
+// +build !linux
+
+package can
+
+import "github.com/pkg/errors"
+
+// SocketCAN is only supported on Linux.
+type SocketCAN struct{}
+
+// OpenSocketCAN always fails on platforms other than Linux.
+func OpenSocketCAN(iface string) (*SocketCAN, error) {
+	return nil, errors.New("socketcan is only supported on linux")
+}
+
+func (s *SocketCAN) Read() (Record, error) {
+	return Record{}, errors.New("socketcan is only supported on linux")
+}
+
+func (s *SocketCAN) Write(frame Frame) error {
+	return errors.New("socketcan is only supported on linux")
+}
+
+func (s *SocketCAN) Close() error {
+	return nil
+}
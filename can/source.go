@@ -0,0 +1,124 @@
+package can
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Source is a live stream of CAN frames. Read blocks until the next frame is
+// available.
+type Source interface {
+	RecordReader
+	Close() error
+}
+
+// OpenSource opens the frame source described by uri. http:// and https://
+// URIs stream CSV frames from the canbus device and socketcan://<interface>
+// reads from a SocketCAN interface such as can0 or vcan0. The source is
+// closed when ctx is cancelled.
+func OpenSource(ctx context.Context, uri string) (Source, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	var source Source
+	switch u.Scheme {
+	case "http", "https":
+		source, err = OpenHTTPSource(ctx, uri)
+	case "socketcan":
+		source, err = OpenSocketCAN(u.Host)
+	default:
+		return nil, errors.Errorf("unknown frame source %q", uri)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return closeOnDone(ctx, source), nil
+}
+
+// ctxSource closes a source when a context is done.
+type ctxSource struct {
+	Source
+	once sync.Once
+	err  error
+	done chan struct{}
+}
+
+// closeOnDone closes source when ctx is done. The goroutine waiting on ctx
+// exits once the source is closed so reconnecting doesn't leak them.
+func closeOnDone(ctx context.Context, source Source) Source {
+	s := &ctxSource{Source: source, done: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Close()
+		case <-s.done:
+		}
+	}()
+	return s
+}
+
+func (s *ctxSource) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+		s.err = s.Source.Close()
+	})
+	return s.err
+}
+
+// HTTPSource reads frames as CSV rows streamed over HTTP.
+type HTTPSource struct {
+	resp   *http.Response
+	reader *csv.Reader
+}
+
+// OpenHTTPSource starts streaming frames from the canbus device at addr.
+func OpenHTTPSource(ctx context.Context, addr string) (*HTTPSource, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", addr, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.Errorf("%s: %s", addr, resp.Status)
+	}
+	reader := csv.NewReader(resp.Body)
+	// Classic and CAN FD rows have different numbers of columns.
+	reader.FieldsPerRecord = -1
+	return &HTTPSource{
+		resp:   resp,
+		reader: reader,
+	}, nil
+}
+
+// Read returns the next frame, timestamped with the time it was received.
+func (s *HTTPSource) Read() (Record, error) {
+	row, err := s.reader.Read()
+	if err != nil {
+		return Record{}, err
+	}
+	frame, err := ParseCSV(row)
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{
+		Frame: frame,
+		Time:  time.Now(),
+	}, nil
+}
+
+func (s *HTTPSource) Close() error {
+	return s.resp.Body.Close()
+}
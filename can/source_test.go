@@ -0,0 +1,87 @@
+package can
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHTTPSourceMixedFrames(t *testing.T) {
+	frames := []Frame{
+		{ID: 0x118, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}, Timestamp: 1},
+		{ID: 0x3D2, FD: true, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, Timestamp: 2},
+		{ID: 0x12345678, Extended: true, Data: make([]byte, 8), Timestamp: 3},
+		{ID: 0x1, FD: true, Data: make([]byte, 64), Timestamp: 4},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := csv.NewWriter(w)
+		for _, frame := range frames {
+			writer.Write(FormatCSV(frame))
+		}
+		writer.Flush()
+	}))
+	defer ts.Close()
+
+	source, err := OpenHTTPSource(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	for i, want := range frames {
+		record, err := source.Read()
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if !reflect.DeepEqual(record.Frame, want) {
+			t.Errorf("%d: Read() = %+v; expected %+v", i, record.Frame, want)
+		}
+	}
+	if _, err := source.Read(); err != io.EOF {
+		t.Errorf("Read() at end = %v; expected EOF", err)
+	}
+}
+
+type fakeSource struct {
+	closes chan struct{}
+}
+
+func (s *fakeSource) Read() (Record, error) {
+	return Record{}, io.EOF
+}
+
+func (s *fakeSource) Close() error {
+	s.closes <- struct{}{}
+	return nil
+}
+
+func TestCloseOnDone(t *testing.T) {
+	// Closing the source closes the underlying source once.
+	fake := &fakeSource{closes: make(chan struct{}, 2)}
+	ctx, cancel := context.WithCancel(context.Background())
+	source := closeOnDone(ctx, fake)
+	source.Close()
+	source.Close()
+	cancel()
+	<-fake.closes
+	select {
+	case <-fake.closes:
+		t.Error("source closed twice")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Cancelling the context closes the source.
+	fake = &fakeSource{closes: make(chan struct{}, 2)}
+	ctx, cancel = context.WithCancel(context.Background())
+	closeOnDone(ctx, fake)
+	cancel()
+	select {
+	case <-fake.closes:
+	case <-time.After(time.Second):
+		t.Error("source wasn't closed when the context was cancelled")
+	}
+}
@@ -0,0 +1,178 @@
+package can
+
+import (
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Smoothing factors for the inter-arrival time and its deviation, the same as
+// TCP uses for round trip times (RFC 6298).
+const (
+	intervalAlpha = 1.0 / 8
+	jitterBeta    = 1.0 / 4
+)
+
+// IDStats are the statistics of the frames seen with a single ID.
+type IDStats struct {
+	ID       int
+	Extended bool
+	Count    int
+	First    time.Time
+	Last     time.Time
+	// DLC is the data length of the most recent frame.
+	DLC int
+	// ChangeMask has a bit set for every data bit that has changed since the
+	// first frame. It's as long as the longest frame seen.
+	ChangeMask []byte
+	// Interval is the smoothed time between frames.
+	Interval time.Duration
+	// Jitter is the smoothed deviation of the time between frames.
+	Jitter time.Duration
+
+	data []byte
+}
+
+// Rate is the number of frames per second based on the smoothed interval.
+func (s IDStats) Rate() float64 {
+	if s.Interval <= 0 {
+		return 0
+	}
+	return float64(time.Second) / float64(s.Interval)
+}
+
+// RateAt is the number of frames per second at now. Once a frame is overdue
+// the rate decays with the time since the last one and it's 0 once the ID has
+// been quiet for longer than the interval plus four times the jitter, or
+// twice the interval, whichever is longer.
+func (s IDStats) RateAt(now time.Time) float64 {
+	since := now.Sub(s.Last)
+	if s.Interval <= 0 || since <= s.Interval {
+		return s.Rate()
+	}
+	stale := s.Interval + 4*s.Jitter
+	if stale < 2*s.Interval {
+		stale = 2 * s.Interval
+	}
+	if since > stale {
+		return 0
+	}
+	return float64(time.Second) / float64(since)
+}
+
+// Key returns the key of the message the statistics are for.
+func (s IDStats) Key() MessageKey {
+	return MessageKey{ID: s.ID, Extended: s.Extended}
+}
+
+// ChangingBits is the number of data bits that have changed.
+func (s IDStats) ChangingBits() int {
+	n := 0
+	for _, b := range s.ChangeMask {
+		n += bits.OnesCount8(b)
+	}
+	return n
+}
+
+// FrameBits is the approximate number of bits a frame with n data bytes takes
+// on the bus, excluding bit stuffing. CAN FD frames are counted as if the data
+// phase ran at the nominal bit rate.
+func FrameBits(extended bool, n int) int {
+	if extended {
+		return 67 + 8*n
+	}
+	return 47 + 8*n
+}
+
+// Stats tracks per ID frame statistics. Standard and extended frames with the
+// same ID are tracked separately. It's safe for concurrent use.
+type Stats struct {
+	mu  sync.Mutex
+	ids map[MessageKey]*IDStats
+}
+
+func NewStats() *Stats {
+	return &Stats{ids: map[MessageKey]*IDStats{}}
+}
+
+// Add records a frame and returns the updated statistics for its ID.
+func (s *Stats) Add(record Record) IDStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frame := record.Frame
+	st, ok := s.ids[frame.Key()]
+	if !ok {
+		st = &IDStats{
+			ID:       frame.ID,
+			Extended: frame.Extended,
+			First:    record.Time,
+			data:     append([]byte(nil), frame.Data...),
+		}
+		s.ids[frame.Key()] = st
+	} else {
+		interval := record.Time.Sub(st.Last)
+		if st.Count == 1 {
+			st.Interval = interval
+			st.Jitter = interval / 2
+		} else {
+			deviation := interval - st.Interval
+			if deviation < 0 {
+				deviation = -deviation
+			}
+			st.Jitter += time.Duration(jitterBeta * float64(deviation-st.Jitter))
+			st.Interval += time.Duration(intervalAlpha * float64(interval-st.Interval))
+		}
+	}
+	st.Count++
+	st.Last = record.Time
+	st.DLC = len(frame.Data)
+	if n := len(frame.Data); n > len(st.ChangeMask) {
+		st.ChangeMask = append(st.ChangeMask, make([]byte, n-len(st.ChangeMask))...)
+	}
+	for i, b := range frame.Data {
+		var prev byte
+		if i < len(st.data) {
+			prev = st.data[i]
+		}
+		st.ChangeMask[i] |= b ^ prev
+	}
+	st.data = append(st.data[:0], frame.Data...)
+	out := *st
+	out.ChangeMask = append([]byte(nil), st.ChangeMask...)
+	return out
+}
+
+// IDs returns the statistics for every ID, most frequent first.
+func (s *Stats) IDs() []IDStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]IDStats, 0, len(s.ids))
+	for _, st := range s.ids {
+		cp := *st
+		cp.ChangeMask = append([]byte(nil), st.ChangeMask...)
+		out = append(out, cp)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		if out[i].ID != out[j].ID {
+			return out[i].ID < out[j].ID
+		}
+		return !out[i].Extended && out[j].Extended
+	})
+	return out
+}
+
+// BusLoad estimates the fraction of a bus running at bitrate bits per second
+// used by the frames, based on each ID's rate at now.
+func (s *Stats) BusLoad(bitrate float64, now time.Time) float64 {
+	var used float64
+	for _, st := range s.IDs() {
+		used += st.RateAt(now) * float64(FrameBits(st.Extended, st.DLC))
+	}
+	return used / bitrate
+}
@@ -0,0 +1,67 @@
+package can
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestStatsRateDecays(t *testing.T) {
+	s := NewStats()
+	start := time.Unix(1600000000, 0)
+	var last time.Time
+	for i := 0; i < 20; i++ {
+		last = start.Add(time.Duration(i) * 100 * time.Millisecond)
+		s.Add(Record{Frame: Frame{ID: 0x118, Data: make([]byte, 8)}, Time: last})
+	}
+	st := s.IDs()[0]
+	if rate := st.Rate(); math.Abs(rate-10) > 1e-6 {
+		t.Fatalf("Rate() = %f; expected 10", rate)
+	}
+
+	cases := []struct {
+		since time.Duration
+		want  float64
+	}{
+		{0, 10},
+		{100 * time.Millisecond, 10},
+		// Overdue frames slow the rate down.
+		{160 * time.Millisecond, 6.25},
+		{200 * time.Millisecond, 5},
+		// The ID stopped sending.
+		{300 * time.Millisecond, 0},
+		{time.Hour, 0},
+	}
+	for _, c := range cases {
+		if got := st.RateAt(last.Add(c.since)); math.Abs(got-c.want) > 1e-6 {
+			t.Errorf("RateAt(+%s) = %f; expected %f", c.since, got, c.want)
+		}
+	}
+
+	// 10 frames of 111 bits a second on a 1000 bit/s bus.
+	if got := s.BusLoad(1000, last); math.Abs(got-1.11) > 1e-6 {
+		t.Errorf("BusLoad() = %f; expected 1.11", got)
+	}
+	if got := s.BusLoad(1000, last.Add(time.Minute)); got != 0 {
+		t.Errorf("BusLoad() of a quiet bus = %f; expected 0", got)
+	}
+}
+
+func TestStatsExtended(t *testing.T) {
+	s := NewStats()
+	start := time.Unix(1600000000, 0)
+	s.Add(Record{Frame: Frame{ID: 0x100, Data: []byte{1}}, Time: start})
+	s.Add(Record{Frame: Frame{ID: 0x100, Extended: true, Data: []byte{1, 2}}, Time: start})
+	s.Add(Record{Frame: Frame{ID: 0x100, Data: []byte{1}}, Time: start.Add(time.Second)})
+
+	ids := s.IDs()
+	if len(ids) != 2 {
+		t.Fatalf("IDs() = %+v; expected standard and extended 0x100", ids)
+	}
+	if ids[0].Extended || ids[0].Count != 2 || ids[0].DLC != 1 {
+		t.Errorf("standard stats = %+v", ids[0])
+	}
+	if !ids[1].Extended || ids[1].Count != 1 || ids[1].DLC != 2 {
+		t.Errorf("extended stats = %+v", ids[1])
+	}
+}
@@ -1,46 +1,38 @@
 package main
 
 import (
-	"encoding/csv"
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
-	"time"
 
 	"github.com/d4l3k/ricela/can"
 )
 
+var canAddr = flag.String("canaddr", "http://192.168.123.10", "frame source, http:// for the canbus device or socketcan://<interface>")
+
 func main() {
+	flag.Parse()
+
 	if err := run(); err != nil {
 		log.Fatalf("%+v", err)
 	}
 }
 
 func run() error {
-	resp, err := http.Get("http://192.168.123.10")
+	source, err := can.OpenSource(context.Background(), *canAddr)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	reader := csv.NewReader(resp.Body)
+	defer source.Close()
 
 	for {
-		row, err := reader.Read()
-		if err != nil {
-			return err
-		}
-
-		frame, err := can.ParseCSV(row)
+		record, err := source.Read()
 		if err != nil {
 			return err
 		}
 
-		record := can.Record{
-			Frame: frame,
-			Time:  time.Now(),
-		}
-
 		bytes, err := json.Marshal(record)
 		if err != nil {
 			return err
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+	"github.com/pkg/errors"
+)
+
+// candidate is a bit range that may hold the reference signal, along with the
+// linear fit from its raw value to the reference.
+type candidate struct {
+	signal      can.Signal
+	correlation float64
+	// toggling is the number of bits in the range that change.
+	toggling int
+	factor   float64
+	offset   float64
+}
+
+func (c candidate) orderName() string {
+	if c.signal.ByteOrder == can.BigEndian {
+		return "big"
+	}
+	return "little"
+}
+
+// dbcLine formats the candidate as a DBC signal definition.
+func (c candidate) dbcLine(name string) string {
+	order, sign := 1, "+"
+	if c.signal.ByteOrder == can.BigEndian {
+		order = 0
+	}
+	if c.signal.Signed {
+		sign = "-"
+	}
+	return fmt.Sprintf("SG_ %s : %d|%d@%d%s (%g,%g)", name, c.signal.Start, c.signal.Length, order, sign, c.factor, c.offset)
+}
+
+// goCall formats the candidate as the Frame method call that decodes it.
+func (c candidate) goCall() string {
+	s := c.signal
+	if s.ByteOrder == can.LittleEndian && !s.Signed {
+		return fmt.Sprintf("frame.ReadFloat(%d, %d, %g, %g)", s.Length, s.Start, c.offset, c.factor)
+	}
+	method := "ReadBits"
+	if s.ByteOrder == can.BigEndian {
+		method += "BigEndian"
+	}
+	if s.Signed {
+		method = "ReadSigned" + method[len("Read"):]
+	}
+	return fmt.Sprintf("float64(frame.%s(%d, %d))*%g + %g", method, s.Length, s.Start, c.factor, c.offset)
+}
+
+// fits reports whether a field of n bits starting at start is within a frame
+// of size bits.
+func fits(start, n, size int, order can.ByteOrder) bool {
+	if order == can.BigEndian {
+		return start/8*8+7-start%8+n <= size
+	}
+	return start+n <= size
+}
+
+// correlate returns the Pearson correlation between xs and ys along with the
+// least squares fit ys = factor*xs + offset.
+func correlate(xs, ys []float64) (r, factor, offset float64) {
+	n := float64(len(xs))
+	var sx, sy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+	}
+	mx, my := sx/n, sy/n
+	var cov, vx, vy float64
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+	if vx == 0 || vy == 0 {
+		return 0, 0, 0
+	}
+	factor = cov / vx
+	return cov / math.Sqrt(vx*vy), factor, my - factor*mx
+}
+
+// bits analyzes the frames of a single ID read from stdin, showing how often
+// each bit toggles and which bit ranges best correlate with a known signal.
+func bits(args []string) error {
+	fs := flag.NewFlagSet("bits", flag.ExitOnError)
+	idFlag := fs.String("id", "", "frame ID to analyze, such as 0x257")
+	ref := fs.String("ref", "", "decoded signal to correlate against, such as "+can.SignedSpeedKey)
+	minLen := fs.Int("minlen", 4, "minimum candidate length in bits")
+	maxLen := fs.Int("maxlen", 16, "maximum candidate length in bits")
+	top := fs.Int("top", 10, "number of candidates to show")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := strconv.ParseInt(*idFlag, 0, 64)
+	if err != nil {
+		return errors.Wrapf(err, "invalid id %q", *idFlag)
+	}
+	if *minLen < 1 || *maxLen > 64 || *minLen > *maxLen {
+		return errors.New("invalid candidate lengths")
+	}
+
+	db, err := loadDBC()
+	if err != nil {
+		return err
+	}
+	if *ref != "" && db.Signal(*ref) == nil {
+		return errors.Errorf("unknown reference signal %q", *ref)
+	}
+	reader, _, err := can.OpenRecordReader(os.Stdin)
+	if err != nil {
+		return err
+	}
+	records, err := can.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	// Pair each frame with the latest value of the reference signal.
+	var frames []can.Frame
+	var refs []float64
+	var times []time.Time
+	var refValue float64
+	haveRef := false
+	for _, record := range records {
+		if *ref != "" {
+			for _, v := range db.DecodeValues(record.Frame) {
+				if v.Signal.Name == *ref {
+					refValue, haveRef = v.Value, true
+				}
+			}
+		}
+		if record.Frame.ID != int(id) {
+			continue
+		}
+		if *ref != "" && !haveRef {
+			continue
+		}
+		frames = append(frames, record.Frame)
+		refs = append(refs, refValue)
+		times = append(times, record.Time)
+	}
+	if len(frames) == 0 {
+		return errors.Errorf("no frames with id 0x%X", id)
+	}
+
+	printToggles(frames)
+	if *ref == "" {
+		return nil
+	}
+
+	candidates := findCandidates(frames, refs, *minLen, *maxLen)
+	if len(candidates) > *top {
+		candidates = candidates[:*top]
+	}
+	fmt.Printf("\n# candidates for %s over %s\n", *ref, times[len(times)-1].Sub(times[0]))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "start\tlength\torder\tsigned\tcorrelation\tdbc\tgo")
+	for _, c := range candidates {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%t\t%.4f\t%s\t%s\n",
+			c.signal.Start, c.signal.Length, c.orderName(), c.signal.Signed,
+			c.correlation, c.dbcLine(*ref), c.goCall())
+	}
+	return w.Flush()
+}
+
+// printToggles prints how many times each bit changed between consecutive
+// frames as a grid with a row per byte and the most significant bit first.
+func printToggles(frames []can.Frame) {
+	toggles := countToggles(frames)
+
+	fmt.Printf("# bit toggles over %d frames\n", len(frames))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "byte\t")
+	for bit := 7; bit >= 0; bit-- {
+		fmt.Fprintf(w, "%d\t", bit)
+	}
+	fmt.Fprintln(w)
+	for b := 0; b < len(toggles)/8; b++ {
+		fmt.Fprintf(w, "%d\t", b)
+		for bit := 7; bit >= 0; bit-- {
+			fmt.Fprintf(w, "%d\t", toggles[b*8+bit])
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+// countToggles counts how many times each bit, numbered little endian,
+// changed between consecutive frames. It has a count for every bit of the
+// longest frame.
+func countToggles(frames []can.Frame) []int {
+	size := 0
+	for _, frame := range frames {
+		if len(frame.Data) > size {
+			size = len(frame.Data)
+		}
+	}
+	toggles := make([]int, 8*size)
+	byteAt := func(frame can.Frame, i int) byte {
+		if i < len(frame.Data) {
+			return frame.Data[i]
+		}
+		return 0
+	}
+	for i := 1; i < len(frames); i++ {
+		for b := 0; b < size; b++ {
+			changed := byteAt(frames[i], b) ^ byteAt(frames[i-1], b)
+			for bit := 0; bit < 8; bit++ {
+				if changed&(1<<uint(bit)) != 0 {
+					toggles[b*8+bit]++
+				}
+			}
+		}
+	}
+	return toggles
+}
+
+// countToggling returns how many of the toggling bits are within the signal.
+func countToggling(signal can.Signal, toggles []int) int {
+	signal.Signed = false
+	n := 0
+	for bit, count := range toggles {
+		if count == 0 {
+			continue
+		}
+		frame := can.Frame{Data: make([]byte, len(toggles)/8)}
+		frame.Data[bit/8] = 1 << uint(bit%8)
+		if signal.Raw(frame) != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// findCandidates ranks every bit range by how well it correlates with refs.
+// Ranges that correlate equally well are ranked by how many of the toggling
+// bits they cover and then by length, since adding constant bits to a range
+// doesn't change its correlation.
+func findCandidates(frames []can.Frame, refs []float64, minLen, maxLen int) []candidate {
+	toggles := countToggles(frames)
+	var candidates []candidate
+	raw := make([]float64, len(frames))
+	for _, order := range []can.ByteOrder{can.LittleEndian, can.BigEndian} {
+		for _, signed := range []bool{false, true} {
+			for n := minLen; n <= maxLen; n++ {
+				for start := 0; start < len(toggles); start++ {
+					if !fits(start, n, len(toggles), order) {
+						continue
+					}
+					signal := can.Signal{
+						Start:     start,
+						Length:    n,
+						ByteOrder: order,
+						Signed:    signed,
+					}
+					for i, frame := range frames {
+						raw[i] = signal.Raw(frame)
+					}
+					r, factor, offset := correlate(raw, refs)
+					if r == 0 {
+						continue
+					}
+					candidates = append(candidates, candidate{
+						signal:      signal,
+						correlation: r,
+						toggling:    countToggling(signal, toggles),
+						factor:      factor,
+						offset:      offset,
+					})
+				}
+			}
+		}
+	}
+	const epsilon = 1e-9
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		ra, rb := math.Abs(a.correlation), math.Abs(b.correlation)
+		if math.Abs(ra-rb) > epsilon {
+			return ra > rb
+		}
+		if a.toggling != b.toggling {
+			return a.toggling > b.toggling
+		}
+		return a.signal.Length < b.signal.Length
+	})
+	return candidates
+}
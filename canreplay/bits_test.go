@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCorrelate(t *testing.T) {
+	cases := []struct {
+		name              string
+		xs, ys            []float64
+		r, factor, offset float64
+	}{
+		{"linear", []float64{0, 1, 2, 3}, []float64{1, 3, 5, 7}, 1, 2, 1},
+		{"inverse", []float64{0, 1, 2, 3}, []float64{10, 9.5, 9, 8.5}, -1, -0.5, 10},
+		{"constant x", []float64{2, 2, 2}, []float64{1, 2, 3}, 0, 0, 0},
+		{"constant y", []float64{1, 2, 3}, []float64{4, 4, 4}, 0, 0, 0},
+		{"uncorrelated", []float64{-1, 0, 1, 0}, []float64{0, 1, 0, -1}, 0, 0, 0},
+	}
+	for _, c := range cases {
+		r, factor, offset := correlate(c.xs, c.ys)
+		if math.Abs(r-c.r) > 1e-9 || math.Abs(factor-c.factor) > 1e-9 || math.Abs(offset-c.offset) > 1e-9 {
+			t.Errorf("%s: correlate(%v, %v) = %g, %g, %g; expected %g, %g, %g", c.name, c.xs, c.ys, r, factor, offset, c.r, c.factor, c.offset)
+		}
+	}
+
+	// A noisy fit is still recovered with a correlation below 1.
+	xs := []float64{0, 1, 2, 3, 4, 5}
+	ys := []float64{0.1, 1.9, 4.2, 5.8, 8.1, 9.9}
+	r, factor, _ := correlate(xs, ys)
+	if r < 0.99 || r >= 1 || math.Abs(factor-2) > 0.1 {
+		t.Errorf("correlate(%v, %v) = %g, %g; expected r just below 1 and factor about 2", xs, ys, r, factor)
+	}
+}
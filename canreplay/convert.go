@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/d4l3k/ricela/can"
+)
+
+func formatNames() string {
+	return fmt.Sprint(can.Formats)
+}
+
+// convert reads a log from stdin and writes it to stdout in another format.
+func convert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	from := fs.String("from", "", "input format "+formatNames()+", detected if empty")
+	to := fs.String("to", string(can.FormatJSON), "output format "+formatNames())
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var reader can.RecordReader
+	var err error
+	if *from == "" {
+		reader, _, err = can.OpenRecordReader(os.Stdin)
+	} else {
+		reader, err = can.NewRecordReader(can.Format(*from), os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+
+	writer, err := can.NewRecordWriter(can.Format(*to), os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
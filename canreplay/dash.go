@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+	"github.com/guptarohit/asciigraph"
+)
+
+const (
+	clearScreen     = "\x1b[H\x1b[2J"
+	enterAltScreen  = "\x1b[?1049h\x1b[?25l"
+	leaveAltScreen  = "\x1b[?25h\x1b[?1049l"
+	dashSparkHeight = 3
+)
+
+// gauge is a bar showing the sum of one or more signals.
+type gauge struct {
+	label string
+	keys  []string
+}
+
+var dashGauges = []gauge{
+	{label: "speed", keys: []string{can.SignedSpeedKey}},
+	{label: "power", keys: []string{can.FrontPowerKey, can.RearPowerKey}},
+	{label: "soc", keys: []string{"ui_state_of_charge_pct"}},
+	{label: "battery temp", keys: []string{"min_batt_temp_c"}},
+	{label: "inverter temp", keys: []string{"inverter_temp_c"}},
+	{label: "stator temp", keys: []string{"stator_temp_c"}},
+	{label: "outside temp", keys: []string{"ambient_temp_filtered_c"}},
+}
+
+// dashboard holds the latest values and the sparkline history of the
+// filtered signals.
+type dashboard struct {
+	db     *can.DBC
+	filter *regexp.Regexp
+	width  int
+	max    int
+
+	mu      sync.Mutex
+	latest  map[string]float64
+	signals map[string]*can.Signal
+	history map[string][]float64
+	last    time.Time
+	frames  int
+	err     error
+}
+
+func (d *dashboard) update(record can.Record) {
+	values := d.db.DecodeValues(record.Frame)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.frames++
+	d.last = record.Time
+	for _, v := range values {
+		d.latest[v.Signal.Name] = v.Value
+		d.signals[v.Signal.Name] = v.Signal
+	}
+}
+
+// tick appends the latest value of each filtered signal to its sparkline.
+func (d *dashboard) tick() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, value := range d.latest {
+		if !d.filter.MatchString(key) {
+			continue
+		}
+		history := append(d.history[key], value)
+		if len(history) > d.width {
+			history = history[len(history)-d.width:]
+		}
+		d.history[key] = history
+	}
+}
+
+func (d *dashboard) render(w io.Writer) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var buf bytes.Buffer
+	buf.WriteString(clearScreen)
+	fmt.Fprintf(&buf, "%s  frames: %d\n\n", d.last.Format("2006-01-02 15:04:05.000"), d.frames)
+
+	for _, g := range dashGauges {
+		d.renderGauge(&buf, g)
+	}
+
+	var keys []string
+	for key := range d.history {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) > d.max {
+		keys = keys[:d.max]
+	}
+	for _, key := range keys {
+		sig := d.signals[key]
+		fmt.Fprintf(&buf, "\n# %s = %s\n", key, can.Value{Signal: sig, Value: d.latest[key]})
+		history := d.history[key]
+		if len(history) < 2 || allSame(history) {
+			continue
+		}
+		buf.WriteString(asciigraph.Plot(history, asciigraph.Height(dashSparkHeight), asciigraph.Width(d.width)))
+		buf.WriteByte('\n')
+	}
+
+	if d.err != nil {
+		fmt.Fprintf(&buf, "\n%+v\n", d.err)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// renderGauge draws a bar scaled to the range of the gauge's signals.
+func (d *dashboard) renderGauge(buf *bytes.Buffer, g gauge) {
+	var value, min, max float64
+	var unit string
+	seen := false
+	for _, key := range g.keys {
+		sig := d.db.Signal(key)
+		if sig == nil {
+			continue
+		}
+		min += sig.Min
+		max += sig.Max
+		unit = sig.Unit
+		if v, ok := d.latest[key]; ok {
+			value += v
+			seen = true
+		}
+	}
+
+	const barWidth = 40
+	fmt.Fprintf(buf, "%-14s", g.label)
+	if !seen || max <= min {
+		fmt.Fprintf(buf, "%10s\n", "-")
+		return
+	}
+	frac := math.Max(0, math.Min(1, (value-min)/(max-min)))
+	filled := int(frac * barWidth)
+	fmt.Fprintf(buf, "%10.1f %-4s [%s%s]\n", value, unit,
+		strings.Repeat("#", filled), strings.Repeat("-", barWidth-filled))
+}
+
+// dash shows a live dashboard of a frame source, or of a recording read from
+// stdin replayed in real time.
+func dash(args []string) error {
+	fs := flag.NewFlagSet("dash", flag.ExitOnError)
+	canAddr := fs.String("canaddr", "", "frame source, http:// for the canbus device or socketcan://<interface>, reads a recording from stdin if empty")
+	speed := fs.Float64("speed", 1, "replay speed of the recording, 0 for as fast as possible")
+	dashFilter := fs.String("filter", "", "regexp filter for the keys to draw sparklines for, empty for all of them")
+	refresh := fs.Duration("refresh", 250*time.Millisecond, "time between redraws")
+	width := fs.Int("width", 80, "width of the sparklines")
+	maxGraphs := fs.Int("maxgraphs", 6, "maximum number of sparklines to draw")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := loadDBC()
+	if err != nil {
+		return err
+	}
+	d := &dashboard{
+		db:      db,
+		width:   *width,
+		max:     *maxGraphs,
+		latest:  map[string]float64{},
+		signals: map[string]*can.Signal{},
+		history: map[string][]float64{},
+	}
+	// An empty filter matches every signal.
+	if d.filter, err = regexp.Compile(*dashFilter); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	var reader can.RecordReader
+	if *canAddr != "" {
+		source, err := can.OpenSource(ctx, *canAddr)
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+		reader = source
+	} else {
+		recording, _, err := can.OpenRecordReader(os.Stdin)
+		if err != nil {
+			return err
+		}
+		reader = can.NewPacedReader(ctx, recording, *speed)
+	}
+
+	go func() {
+		for {
+			record, err := reader.Read()
+			if err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					d.mu.Lock()
+					d.err = err
+					d.mu.Unlock()
+				}
+				return
+			}
+			d.update(record)
+		}
+	}()
+
+	fmt.Print(enterAltScreen)
+	defer fmt.Print(leaveAltScreen)
+
+	ticker := time.NewTicker(*refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		d.tick()
+		if err := d.render(os.Stdout); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/d4l3k/ricela/can"
+)
+
+func TestDashboardTickFilter(t *testing.T) {
+	db, records := testRecords(t)
+	cases := []struct {
+		filter string
+		want   []string
+	}{
+		{"", []string{"a", "b", "skipped"}},
+		{"^[ab]$", []string{"a", "b"}},
+		{"^none$", nil},
+	}
+	for _, c := range cases {
+		d := &dashboard{
+			db:      db,
+			filter:  regexp.MustCompile(c.filter),
+			width:   2,
+			latest:  map[string]float64{},
+			signals: map[string]*can.Signal{},
+			history: map[string][]float64{},
+		}
+		for _, record := range records {
+			d.update(record)
+			d.tick()
+		}
+		var keys []string
+		for key := range d.history {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, c.want) {
+			t.Errorf("filter %q: history keys = %v; expected %v", c.filter, keys, c.want)
+		}
+		if len(keys) > 0 {
+			if history := d.history["a"]; !reflect.DeepEqual(history, []float64{20, 40}) {
+				t.Errorf("filter %q: a history = %v; expected the last %d values", c.filter, history, d.width)
+			}
+		}
+	}
+}
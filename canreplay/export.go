@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+	"github.com/pkg/errors"
+)
+
+// Interpolation modes for resampling.
+const (
+	interpHold   = "hold"
+	interpLinear = "linear"
+)
+
+// Export formats.
+const (
+	exportCSV      = "csv"
+	exportColumnar = "columnar"
+)
+
+type samplePoint struct {
+	t time.Time
+	v float64
+}
+
+// table is a set of signals resampled onto a common time grid. Missing
+// values, before a signal is first seen, are NaN.
+type table struct {
+	times   []time.Time
+	signals []*can.Signal
+	columns [][]float64
+}
+
+// resample decodes the records and samples every signal matching filter
+// every step. With hold interpolation each sample is the last value seen, with
+// linear interpolation it's interpolated between the surrounding values.
+func resample(db *can.DBC, records []can.Record, filter *regexp.Regexp, step time.Duration, interp string) (*table, error) {
+	if interp != interpHold && interp != interpLinear {
+		return nil, errors.Errorf("unknown interpolation %q, expected %s or %s", interp, interpHold, interpLinear)
+	}
+	if step <= 0 {
+		return nil, errors.New("rate must be positive")
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no records")
+	}
+
+	series := map[string][]samplePoint{}
+	signals := map[string]*can.Signal{}
+	for _, record := range records {
+		for _, v := range db.DecodeValues(record.Frame) {
+			name := v.Signal.Name
+			if !filter.MatchString(name) {
+				continue
+			}
+			series[name] = append(series[name], samplePoint{t: record.Time, v: v.Value})
+			signals[name] = v.Signal
+		}
+	}
+
+	var names []string
+	for name := range series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t := &table{}
+	start := records[0].Time
+	end := records[len(records)-1].Time
+	for ts := start; !ts.After(end); ts = ts.Add(step) {
+		t.times = append(t.times, ts)
+	}
+
+	for _, name := range names {
+		points := series[name]
+		sort.SliceStable(points, func(i, j int) bool {
+			return points[i].t.Before(points[j].t)
+		})
+		column := make([]float64, len(t.times))
+		// next is the index of the first point after the current time.
+		next := 0
+		for i, ts := range t.times {
+			for next < len(points) && !points[next].t.After(ts) {
+				next++
+			}
+			switch {
+			case next == 0:
+				column[i] = math.NaN()
+			case interp == interpLinear && next < len(points):
+				a, b := points[next-1], points[next]
+				frac := float64(ts.Sub(a.t)) / float64(b.t.Sub(a.t))
+				column[i] = a.v + (b.v-a.v)*frac
+			default:
+				column[i] = points[next-1].v
+			}
+		}
+		t.signals = append(t.signals, signals[name])
+		t.columns = append(t.columns, column)
+	}
+	return t, nil
+}
+
+// writeCSV writes the table as CSV with a time column followed by a column per
+// signal. Missing values are left empty.
+func writeCSV(w io.Writer, t *table) error {
+	cw := csv.NewWriter(w)
+	header := []string{"time"}
+	for _, signal := range t.signals {
+		header = append(header, signal.Name)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(header))
+	for i, ts := range t.times {
+		row[0] = ts.Format(time.RFC3339Nano)
+		for j, column := range t.columns {
+			if math.IsNaN(column[i]) {
+				row[j+1] = ""
+			} else {
+				row[j+1] = strconv.FormatFloat(column[i], 'g', -1, 64)
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// The columnar format is the magic line followed by a JSON header line and
+// then each column's values back to back as little endian arrays of Rows
+// elements. The time column holds int64 unix nanoseconds and the signal
+// columns float64s with NaN for missing values, so each column can be loaded
+// directly with numpy.frombuffer from the data after the header.
+const columnarMagic = "RCOL1\n"
+
+type columnarHeader struct {
+	Rows    int              `json:"rows"`
+	Columns []columnarColumn `json:"columns"`
+}
+
+type columnarColumn struct {
+	Name string `json:"name"`
+	Unit string `json:"unit,omitempty"`
+	Type string `json:"type"`
+	// Offset is the position of the column relative to the end of the
+	// header line.
+	Offset int64 `json:"offset"`
+}
+
+// writeColumnar writes the table in the columnar format.
+func writeColumnar(w io.Writer, t *table) error {
+	rows := len(t.times)
+	columns := []columnarColumn{{Name: "time", Type: "int64"}}
+	for _, signal := range t.signals {
+		columns = append(columns, columnarColumn{
+			Name: signal.Name,
+			Unit: signal.Unit,
+			Type: "float64",
+		})
+	}
+
+	for i := range columns {
+		columns[i].Offset = int64(i * rows * 8)
+	}
+	body, err := json.Marshal(columnarHeader{Rows: rows, Columns: columns})
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(columnarMagic)
+	bw.Write(body)
+	bw.WriteByte('\n')
+
+	var buf [8]byte
+	for _, ts := range t.times {
+		binary.LittleEndian.PutUint64(buf[:], uint64(ts.UnixNano()))
+		bw.Write(buf[:])
+	}
+	for _, column := range t.columns {
+		for _, v := range column {
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+			bw.Write(buf[:])
+		}
+	}
+	return bw.Flush()
+}
+
+// export reads a log from stdin and writes the decoded signals resampled onto
+// a common time grid to stdout.
+func export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	rate := fs.Float64("rate", 10, "samples per second")
+	interp := fs.String("interp", interpHold, "interpolation between values, "+interpHold+" or "+interpLinear)
+	format := fs.String("format", exportCSV, "output format, "+exportCSV+" or "+exportColumnar)
+	exportFilter := fs.String("filter", "", "regexp filter for the keys")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	re, err := regexp.Compile(*exportFilter)
+	if err != nil {
+		return err
+	}
+	if *rate <= 0 {
+		return errors.New("rate must be positive")
+	}
+	db, err := loadDBC()
+	if err != nil {
+		return err
+	}
+	reader, _, err := can.OpenRecordReader(os.Stdin)
+	if err != nil {
+		return err
+	}
+	records, err := can.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	t, err := resample(db, records, re, time.Duration(float64(time.Second) / *rate), *interp)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case exportCSV:
+		return writeCSV(os.Stdout, t)
+	case exportColumnar:
+		return writeColumnar(os.Stdout, t)
+	default:
+		return errors.Errorf("unknown export format %q", *format)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+)
+
+const testDBC = `VERSION ""
+
+BO_ 1 first: 8 Vector__XXX
+ SG_ a : 0|8@1+ (1,0) [0|255] "V" Vector__XXX
+ SG_ skipped : 8|8@1+ (1,0) [0|255] "" Vector__XXX
+
+BO_ 2 second: 8 Vector__XXX
+ SG_ b : 0|8@1+ (1,0) [0|255] "A" Vector__XXX
+`
+
+func testRecords(t *testing.T) (*can.DBC, []can.Record) {
+	db, err := can.ParseDBC(strings.NewReader(testDBC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1600000000, 0)
+	record := func(offset time.Duration, id int, v byte) can.Record {
+		return can.Record{Time: start.Add(offset), Frame: can.Frame{ID: id, Data: []byte{v, 0, 0, 0, 0, 0, 0, 0}}}
+	}
+	return db, []can.Record{
+		record(0, 1, 10),
+		record(1500*time.Millisecond, 2, 5),
+		record(2*time.Second, 1, 20),
+		record(4*time.Second, 1, 40),
+	}
+}
+
+// sameValues compares columns treating NaNs as equal.
+func sameValues(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.IsNaN(a[i]) != math.IsNaN(b[i]) || !math.IsNaN(a[i]) && math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestResample(t *testing.T) {
+	db, records := testRecords(t)
+	filter := regexp.MustCompile("^[ab]$")
+	nan := math.NaN()
+	cases := []struct {
+		interp string
+		want   [][]float64
+	}{
+		{interpHold, [][]float64{
+			{10, 10, 20, 20, 40},
+			{nan, nan, 5, 5, 5},
+		}},
+		{interpLinear, [][]float64{
+			{10, 15, 20, 30, 40},
+			{nan, nan, 5, 5, 5},
+		}},
+	}
+	for _, c := range cases {
+		out, err := resample(db, records, filter, time.Second, c.interp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out.times) != 5 || !out.times[4].Equal(records[3].Time) {
+			t.Errorf("%s: times = %v; expected 5 samples ending at %v", c.interp, out.times, records[3].Time)
+		}
+		var names []string
+		for _, signal := range out.signals {
+			names = append(names, signal.Name)
+		}
+		if strings.Join(names, ",") != "a,b" {
+			t.Fatalf("%s: signals = %v; expected [a b]", c.interp, names)
+		}
+		for i, column := range out.columns {
+			if !sameValues(column, c.want[i]) {
+				t.Errorf("%s: %s = %v; expected %v", c.interp, names[i], column, c.want[i])
+			}
+		}
+	}
+
+	if _, err := resample(db, records, filter, time.Second, "cubic"); err == nil {
+		t.Error("expected error for unknown interpolation")
+	}
+	if _, err := resample(db, records, filter, 0, interpHold); err == nil {
+		t.Error("expected error for zero step")
+	}
+	if _, err := resample(db, nil, filter, time.Second, interpHold); err == nil {
+		t.Error("expected error for no records")
+	}
+}
+
+func TestWriteColumnar(t *testing.T) {
+	db, records := testRecords(t)
+	tab, err := resample(db, records, regexp.MustCompile("^[ab]$"), time.Second, interpHold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := writeColumnar(&buf, tab); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.Bytes()
+	if !bytes.HasPrefix(out, []byte(columnarMagic)) {
+		t.Fatalf("missing magic: %q", out[:len(columnarMagic)])
+	}
+	out = out[len(columnarMagic):]
+	end := bytes.IndexByte(out, '\n')
+	if end < 0 {
+		t.Fatal("missing header line")
+	}
+	var header columnarHeader
+	if err := json.Unmarshal(out[:end], &header); err != nil {
+		t.Fatal(err)
+	}
+	data := out[end+1:]
+
+	rows := len(tab.times)
+	if header.Rows != rows || len(header.Columns) != 3 {
+		t.Fatalf("header = %+v; expected %d rows and 3 columns", header, rows)
+	}
+	if len(data) != 3*rows*8 {
+		t.Fatalf("got %d bytes of data; expected %d", len(data), 3*rows*8)
+	}
+	want := []columnarColumn{
+		{Name: "time", Type: "int64", Offset: 0},
+		{Name: "a", Unit: "V", Type: "float64", Offset: int64(rows * 8)},
+		{Name: "b", Unit: "A", Type: "float64", Offset: int64(2 * rows * 8)},
+	}
+	for i, column := range header.Columns {
+		if column != want[i] {
+			t.Errorf("column %d = %+v; expected %+v", i, column, want[i])
+		}
+	}
+
+	for i, ts := range tab.times {
+		if v := int64(binary.LittleEndian.Uint64(data[i*8:])); v != ts.UnixNano() {
+			t.Errorf("time[%d] = %d; expected %d", i, v, ts.UnixNano())
+		}
+	}
+	for j, column := range tab.columns {
+		offset := int(header.Columns[j+1].Offset)
+		values := make([]float64, rows)
+		for i := range values {
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[offset+i*8:]))
+		}
+		if !sameValues(values, column) {
+			t.Errorf("%s = %v; expected %v", header.Columns[j+1].Name, values, column)
+		}
+	}
+}
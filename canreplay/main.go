@@ -1,11 +1,8 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"math"
 	"os"
@@ -14,44 +11,70 @@ import (
 
 	"github.com/d4l3k/ricela/can"
 	"github.com/guptarohit/asciigraph"
+	"github.com/pkg/errors"
 )
 
 var (
 	graph       = flag.Bool("graph", false, "whether to graph the series")
 	filter      = flag.String("filter", "", "regexp filter for the keys")
 	hidezero    = flag.Bool("hidezero", false, "hide all zero values")
-	zerotosixty = flag.Bool("zerotosixty", false, "estimate 0-60 times")
+	zerotosixty = flag.Bool("zerotosixty", false, "report 0-60 times, see the perf command for other bands")
+	dbcFile     = flag.String("dbc", "", "DBC file with the signal definitions, defaults to the built-in database")
 )
 
 func main() {
 	flag.Parse()
 
-	if err := run(); err != nil {
+	var err error
+	switch cmd := flag.Arg(0); cmd {
+	case "":
+		err = run()
+	case "convert":
+		err = convert(flag.Args()[1:])
+	case "dash":
+		err = dash(flag.Args()[1:])
+	case "export":
+		err = export(flag.Args()[1:])
+	case "replay":
+		err = replay(flag.Args()[1:])
+	case "bits":
+		err = bits(flag.Args()[1:])
+	case "stats":
+		err = stats(flag.Args()[1:])
+	case "perf":
+		err = perf(flag.Args()[1:])
+	default:
+		err = errors.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
 		log.Fatalf("%+v", err)
 	}
 }
 
-func readAllRecords(r io.Reader) ([]can.Record, error) {
-	var records []can.Record
-	s := bufio.NewScanner(r)
-	for s.Scan() {
-		var record can.Record
-		if err := json.Unmarshal(s.Bytes(), &record); err != nil {
-			return nil, err
-		}
-		records = append(records, record)
+func loadDBC() (*can.DBC, error) {
+	if *dbcFile == "" {
+		return can.DefaultDBC, nil
 	}
-	if err := s.Err(); err != nil {
-		return nil, err
-	}
-	return records, nil
+	return can.LoadDBC(*dbcFile)
 }
 
 func run() error {
-	records, err := readAllRecords(os.Stdin)
+	db, err := loadDBC()
+	if err != nil {
+		return err
+	}
+
+	reader, _, err := can.OpenRecordReader(os.Stdin)
+	if err != nil {
+		return err
+	}
+	records, err := can.ReadAll(reader)
 	if err != nil {
 		return err
 	}
+	if len(records) == 0 {
+		return errors.New("no records")
+	}
 
 	const graphWidth = 80
 
@@ -64,17 +87,14 @@ func run() error {
 		Y float64
 	}
 
-	var speeds []Point
-
 	series := map[string][]Point{}
+	signals := map[string]*can.Signal{}
 
 	for _, record := range records {
 		dur := record.Time.Sub(start)
-		for key, value := range can.FrameToKV(record.Frame) {
+		for _, v := range db.DecodeValues(record.Frame) {
+			key, value := v.Signal.Name, v.Value
 			point := Point{X: dur, Y: value}
-			if key == can.SignedSpeedKey {
-				speeds = append(speeds, point)
-			}
 
 			match, err := regexp.MatchString(*filter, key)
 			if err != nil {
@@ -86,11 +106,12 @@ func run() error {
 
 			if *graph {
 				series[key] = append(series[key], point)
+				signals[key] = v.Signal
 			} else {
 				if *hidezero && value == 0 {
 					continue
 				}
-				fmt.Printf("%s: %s = %f\n", record.Time, key, value)
+				fmt.Printf("%s: %s = %s\n", record.Time, key, v)
 			}
 		}
 	}
@@ -121,37 +142,33 @@ func run() error {
 			data[i] = value
 		}
 
+		signal := signals[key]
 		fmt.Printf("# %s:\n", key)
+		if signal.Description != "" {
+			fmt.Println(signal.Description)
+		}
 		if allSame(data) {
-			fmt.Println("all values the same:", data[0])
+			fmt.Println("all values the same:", can.Value{Signal: signal, Value: data[0]})
 		} else {
+			caption := fmt.Sprintf("%s - %s", start, end)
+			if signal.Unit != "" {
+				caption += fmt.Sprintf(" (%s)", signal.Unit)
+			}
 			fmt.Println(asciigraph.Plot(
 				data,
 				asciigraph.Height(10),
-				asciigraph.Caption(fmt.Sprintf("%s - %s", start, end)),
+				asciigraph.Caption(caption),
 			))
 		}
-		fmt.Printf("max = %f, min = %f\n", max, min)
+		fmt.Printf("max = %s, min = %s\n", can.Value{Signal: signal, Value: max}, can.Value{Signal: signal, Value: min})
 		fmt.Println()
 	}
 
 	if *zerotosixty {
-		var low Point
-		for _, speed := range speeds {
-			if speed.Y < 5 {
-				low = speed
-			}
-			if speed.Y > 60 && low.X != 0 {
-				timepermph := float64(speed.X-low.X) / (speed.Y - low.Y)
-				fmt.Println("start", low)
-				fmt.Println("end", speed)
-
-				adjustedLow := low.X - time.Duration(timepermph*low.Y)
-				adjustedHigh := speed.X + time.Duration(timepermph*(60-speed.Y))
-				fmt.Println("estimated 0-60:", adjustedHigh-adjustedLow)
-
-				low = Point{}
-			}
+		bands := []band{{Name: "0-60", From: 0, To: 60}}
+		runs := analyzeRuns(db, records, bands, defaultRollout)
+		if err := printRuns(os.Stdout, runs, bands); err != nil {
+			return err
 		}
 	}
 
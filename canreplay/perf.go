@@ -0,0 +1,434 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+	"github.com/pkg/errors"
+)
+
+const (
+	feetPerMile  = 5280
+	trapDistance = 66
+
+	// stoppedSpeed is the speed in mph below which the car is stationary.
+	stoppedSpeed = 0.5
+	// runDropSpeed is how far the speed has to fall below its peak to end an
+	// acceleration run.
+	runDropSpeed = 2
+	// minRunGain is the minimum speed gained for a run to be reported.
+	minRunGain = 10
+
+	defaultBands   = "0-30,0-60,50-70,1/4mi"
+	defaultRollout = 1.0
+)
+
+// band is a speed range in mph or, if Distance is set, a standing start
+// distance in feet.
+type band struct {
+	Name     string
+	From, To float64
+	Distance float64
+}
+
+// parseBands parses a comma separated list of speed ranges such as 0-60 and
+// distances such as 1/4mi or 60ft.
+func parseBands(s string) ([]band, error) {
+	var bands []band
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if b, ok, err := parseDistanceBand(part); err != nil {
+			return nil, err
+		} else if ok {
+			bands = append(bands, b)
+			continue
+		}
+
+		speeds := strings.SplitN(part, "-", 2)
+		if len(speeds) != 2 {
+			return nil, errors.Errorf("invalid band %q, expected from-to", part)
+		}
+		from, err := strconv.ParseFloat(speeds[0], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "band %q", part)
+		}
+		to, err := strconv.ParseFloat(speeds[1], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "band %q", part)
+		}
+		if to <= from {
+			return nil, errors.Errorf("invalid band %q, end must be greater than start", part)
+		}
+		bands = append(bands, band{Name: part, From: from, To: to})
+	}
+	return bands, nil
+}
+
+func parseDistanceBand(s string) (band, bool, error) {
+	var unit float64
+	var num string
+	switch {
+	case strings.HasSuffix(s, "mi"):
+		unit, num = feetPerMile, strings.TrimSuffix(s, "mi")
+	case strings.HasSuffix(s, "ft"):
+		unit, num = 1, strings.TrimSuffix(s, "ft")
+	default:
+		return band{}, false, nil
+	}
+
+	denom := 1.0
+	if parts := strings.SplitN(num, "/", 2); len(parts) == 2 {
+		var err error
+		denom, err = strconv.ParseFloat(parts[1], 64)
+		if err != nil || denom == 0 {
+			return band{}, false, errors.Errorf("invalid distance %q", s)
+		}
+		num = parts[0]
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return band{}, false, errors.Errorf("invalid distance %q", s)
+	}
+	return band{Name: s, Distance: n / denom * unit}, true, nil
+}
+
+// BandResult is the time taken to cover a band.
+type BandResult struct {
+	Band    string  `json:"band"`
+	Seconds float64 `json:"seconds"`
+	// TrapMPH is the average speed over the last 66ft of a distance band.
+	TrapMPH float64 `json:"trap_mph,omitempty"`
+}
+
+// AxlePeak is the peak output of a drive unit during a run.
+type AxlePeak struct {
+	TorqueNm float64 `json:"torque_nm"`
+	PowerKW  float64 `json:"power_kw"`
+}
+
+// Run is a single acceleration run.
+type Run struct {
+	Start time.Time `json:"start"`
+	// Launch is set if the run started from a standstill.
+	Launch   bool         `json:"launch"`
+	StartMPH float64      `json:"start_mph"`
+	PeakMPH  float64      `json:"peak_mph"`
+	Bands    []BandResult `json:"bands"`
+	Front    AxlePeak     `json:"front"`
+	Rear     AxlePeak     `json:"rear"`
+	// PeakPowerKW is the peak combined power of both axles.
+	PeakPowerKW float64 `json:"peak_power_kw"`
+}
+
+func (r Run) band(name string) (BandResult, bool) {
+	for _, b := range r.Bands {
+		if b.Band == name {
+			return b, true
+		}
+	}
+	return BandResult{}, false
+}
+
+type speedSample struct {
+	t   time.Time
+	mph float64
+}
+
+// axleSample is the latest torque and rpm of both axles after a frame.
+type axleSample struct {
+	t                     time.Time
+	frontTorque, frontRPM float64
+	rearTorque, rearRPM   float64
+}
+
+// axlePowerKW converts axle torque and speed to mechanical power.
+func axlePowerKW(torqueNm, rpm float64) float64 {
+	return torqueNm * rpm * 2 * math.Pi / 60 / 1000
+}
+
+// analyzeRuns finds every acceleration run in the records and times the bands
+// within them. rollout is the distance in feet a launch travels before the
+// clock starts, like the staging beam at a drag strip.
+func analyzeRuns(db *can.DBC, records []can.Record, bands []band, rollout float64) []Run {
+	var speeds []speedSample
+	var axles []axleSample
+	var axle axleSample
+	for _, record := range records {
+		axleChanged := false
+		for _, v := range db.DecodeValues(record.Frame) {
+			switch v.Signal.Name {
+			case can.SignedSpeedKey:
+				speeds = append(speeds, speedSample{t: record.Time, mph: v.Value})
+			case can.FrontTorqueKey:
+				axle.frontTorque, axleChanged = v.Value, true
+			case can.FrontAxleRPMKey:
+				axle.frontRPM, axleChanged = v.Value, true
+			case can.RearTorqueKey:
+				axle.rearTorque, axleChanged = v.Value, true
+			case can.RearAxleRPMKey:
+				axle.rearRPM, axleChanged = v.Value, true
+			}
+		}
+		if axleChanged {
+			axle.t = record.Time
+			axles = append(axles, axle)
+		}
+	}
+
+	var runs []Run
+	for _, span := range findRuns(speeds) {
+		samples := speeds[span.start : span.end+1]
+		run := timeRun(samples, span.peak-span.start, bands, rollout)
+		if run.PeakMPH-run.StartMPH < minRunGain {
+			continue
+		}
+		peakAxles(&run, axles, samples[0].t, samples[len(samples)-1].t)
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+type runSpan struct {
+	start, peak, end int
+}
+
+// findRuns splits the speed samples into runs where the speed is increasing.
+// A run starts at a local minimum and ends once the speed falls runDropSpeed
+// below its peak.
+func findRuns(speeds []speedSample) []runSpan {
+	var spans []runSpan
+	span := runSpan{}
+	for i := 1; i < len(speeds); i++ {
+		v := speeds[i].mph
+		switch {
+		case v > speeds[span.peak].mph:
+			span.peak = i
+			span.end = i
+		case v <= speeds[span.start].mph && span.peak == span.start:
+			// Still stationary or slowing down, move the start forward.
+			span = runSpan{start: i, peak: i, end: i}
+		case v < speeds[span.peak].mph-runDropSpeed:
+			if span.peak > span.start {
+				spans = append(spans, span)
+			}
+			span = runSpan{start: i, peak: i, end: i}
+		default:
+			span.end = i
+		}
+	}
+	if span.peak > span.start {
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// timeRun times the bands in a run. peak is the index of the fastest sample.
+func timeRun(samples []speedSample, peak int, bands []band, rollout float64) Run {
+	run := Run{
+		Start:    samples[0].t,
+		StartMPH: samples[0].mph,
+		PeakMPH:  samples[peak].mph,
+		Launch:   samples[0].mph < stoppedSpeed,
+	}
+
+	// The car started moving somewhere between the last stationary sample
+	// and the first moving one, extrapolate back to 0 from the initial
+	// acceleration.
+	points := samples
+	if run.Launch && len(samples) > 2 {
+		a, b := samples[1], samples[2]
+		start := samples[0].t
+		if accel := (b.mph - a.mph) / b.t.Sub(a.t).Seconds(); accel > 0 {
+			t0 := a.t.Add(-time.Duration(a.mph / accel * float64(time.Second)))
+			if t0.After(start) {
+				start = t0
+			}
+		}
+		points = append([]speedSample{{t: start}}, samples[1:]...)
+		run.Start = start
+	}
+
+	launch := run.Start
+	if run.Launch && rollout > 0 {
+		if t, ok := timeAtDistance(points, rollout); ok {
+			launch = t
+		}
+	}
+
+	for _, b := range bands {
+		var result BandResult
+		var ok bool
+		if b.Distance > 0 {
+			result, ok = timeDistance(points, launch, rollout, b)
+		} else {
+			result, ok = timeSpeed(points[:peak+1], launch, run.Launch, b)
+		}
+		if ok {
+			run.Bands = append(run.Bands, result)
+		}
+	}
+	return run
+}
+
+func timeSpeed(points []speedSample, launch time.Time, isLaunch bool, b band) (BandResult, bool) {
+	var start time.Time
+	if b.From == 0 {
+		if !isLaunch {
+			return BandResult{}, false
+		}
+		start = launch
+	} else {
+		var ok bool
+		if start, ok = timeAtSpeed(points, b.From); !ok {
+			return BandResult{}, false
+		}
+	}
+	end, ok := timeAtSpeed(points, b.To)
+	if !ok {
+		return BandResult{}, false
+	}
+	return BandResult{Band: b.Name, Seconds: end.Sub(start).Seconds()}, true
+}
+
+func timeDistance(points []speedSample, launch time.Time, rollout float64, b band) (BandResult, bool) {
+	if points[0].mph >= stoppedSpeed {
+		return BandResult{}, false
+	}
+	end, ok := timeAtDistance(points, rollout+b.Distance)
+	if !ok {
+		return BandResult{}, false
+	}
+	result := BandResult{Band: b.Name, Seconds: end.Sub(launch).Seconds()}
+	if b.Distance > trapDistance {
+		if trap, ok := timeAtDistance(points, rollout+b.Distance-trapDistance); ok {
+			fps := trapDistance / end.Sub(trap).Seconds()
+			result.TrapMPH = fps * 3600 / feetPerMile
+		}
+	}
+	return result, true
+}
+
+// timeAtSpeed returns when the speed first crosses mph.
+func timeAtSpeed(points []speedSample, mph float64) (time.Time, bool) {
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		if a.mph < mph && b.mph >= mph {
+			frac := (mph - a.mph) / (b.mph - a.mph)
+			return a.t.Add(time.Duration(frac * float64(b.t.Sub(a.t)))), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// timeAtDistance returns when the distance travelled since the first point
+// reaches feet, integrating the speed with the trapezoidal rule.
+func timeAtDistance(points []speedSample, feet float64) (time.Time, bool) {
+	const fpsPerMPH = feetPerMile / 3600.0
+	var travelled float64
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		dt := b.t.Sub(a.t).Seconds()
+		step := (a.mph + b.mph) / 2 * fpsPerMPH * dt
+		if step > 0 && travelled+step >= feet {
+			frac := (feet - travelled) / step
+			return a.t.Add(time.Duration(frac * float64(b.t.Sub(a.t)))), true
+		}
+		travelled += step
+	}
+	return time.Time{}, false
+}
+
+// peakAxles fills in the peak torque and power of each axle between start and
+// end.
+func peakAxles(run *Run, axles []axleSample, start, end time.Time) {
+	for _, s := range axles {
+		if s.t.Before(start) || s.t.After(end) {
+			continue
+		}
+		front := axlePowerKW(s.frontTorque, s.frontRPM)
+		rear := axlePowerKW(s.rearTorque, s.rearRPM)
+		run.Front.TorqueNm = math.Max(run.Front.TorqueNm, s.frontTorque)
+		run.Front.PowerKW = math.Max(run.Front.PowerKW, front)
+		run.Rear.TorqueNm = math.Max(run.Rear.TorqueNm, s.rearTorque)
+		run.Rear.PowerKW = math.Max(run.Rear.PowerKW, rear)
+		run.PeakPowerKW = math.Max(run.PeakPowerKW, front+rear)
+	}
+}
+
+// printRuns writes the runs as a table with a column per band.
+func printRuns(out io.Writer, runs []Run, bands []band) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprint(w, "#\tstart\tfrom\tpeak")
+	for _, b := range bands {
+		fmt.Fprintf(w, "\t%s", b.Name)
+	}
+	fmt.Fprintln(w, "\tfront Nm/kW\trear Nm/kW\tpeak kW")
+
+	for i, run := range runs {
+		fmt.Fprintf(w, "%d\t%s\t%.1f\t%.1f", i+1, run.Start.Format("2006-01-02 15:04:05.000"), run.StartMPH, run.PeakMPH)
+		for _, b := range bands {
+			result, ok := run.band(b.Name)
+			switch {
+			case !ok:
+				fmt.Fprint(w, "\t-")
+			case result.TrapMPH > 0:
+				fmt.Fprintf(w, "\t%.2fs @ %.1f", result.Seconds, result.TrapMPH)
+			default:
+				fmt.Fprintf(w, "\t%.2fs", result.Seconds)
+			}
+		}
+		fmt.Fprintf(w, "\t%.0f/%.0f\t%.0f/%.0f\t%.0f\n",
+			run.Front.TorqueNm, run.Front.PowerKW,
+			run.Rear.TorqueNm, run.Rear.PowerKW,
+			run.PeakPowerKW)
+	}
+	return w.Flush()
+}
+
+// perf reads a log from stdin and reports every acceleration run in it.
+func perf(args []string) error {
+	fs := flag.NewFlagSet("perf", flag.ExitOnError)
+	bandsFlag := fs.String("bands", defaultBands, "comma separated speed bands in mph such as 0-60 and standing start distances such as 1/4mi or 60ft")
+	rollout := fs.Float64("rollout", defaultRollout, "distance in feet a launch travels before timing starts")
+	asJSON := fs.Bool("json", false, "output a JSON report instead of a table")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	bands, err := parseBands(*bandsFlag)
+	if err != nil {
+		return err
+	}
+	db, err := loadDBC()
+	if err != nil {
+		return err
+	}
+	reader, _, err := can.OpenRecordReader(os.Stdin)
+	if err != nil {
+		return err
+	}
+	records, err := can.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	runs := analyzeRuns(db, records, bands, *rollout)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(runs)
+	}
+	return printRuns(os.Stdout, runs, bands)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFindRuns(t *testing.T) {
+	speeds := func(mph ...float64) []speedSample {
+		start := time.Unix(0, 0)
+		var samples []speedSample
+		for i, v := range mph {
+			samples = append(samples, speedSample{t: start.Add(time.Duration(i) * time.Second), mph: v})
+		}
+		return samples
+	}
+	cases := []struct {
+		name string
+		mph  []float64
+		want []runSpan
+	}{
+		{"empty", nil, nil},
+		{"stationary", []float64{0, 0, 0}, nil},
+		{"slowing", []float64{30, 20, 10, 0}, nil},
+		{"single run", []float64{0, 0, 10, 20, 30}, []runSpan{{start: 1, peak: 4, end: 4}}},
+		// Small drops after the peak stay in the run.
+		{"plateau", []float64{0, 20, 30, 29, 29.5}, []runSpan{{start: 0, peak: 2, end: 4}}},
+		{"two runs", []float64{0, 20, 40, 30, 10, 20, 50, 20}, []runSpan{
+			{start: 0, peak: 2, end: 2},
+			{start: 4, peak: 6, end: 6},
+		}},
+	}
+	for _, c := range cases {
+		if out := findRuns(speeds(c.mph...)); !reflect.DeepEqual(out, c.want) {
+			t.Errorf("%s: findRuns(%v) = %+v; expected %+v", c.name, c.mph, out, c.want)
+		}
+	}
+}
+
+func TestParseBands(t *testing.T) {
+	out, err := parseBands("0-60, 50-70,1/4mi,60ft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []band{
+		{Name: "0-60", From: 0, To: 60},
+		{Name: "50-70", From: 50, To: 70},
+		{Name: "1/4mi", Distance: 1320},
+		{Name: "60ft", Distance: 60},
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("parseBands = %+v; expected %+v", out, want)
+	}
+	for _, s := range []string{"60", "60-50", "a-60", "1/0mi", "-5ft"} {
+		if _, err := parseBands(s); err == nil {
+			t.Errorf("parseBands(%q) expected error", s)
+		}
+	}
+}
+
+// testAccel is the acceleration of the runs in the tests, 0-60 in 6s.
+const (
+	testAccelMPH = 10
+	testAccelFPS = testAccelMPH * feetPerMile / 3600.0
+)
+
+// constantAccel samples a run accelerating at testAccelMPH from startMPH
+// every 100ms for the duration.
+func constantAccel(startMPH float64, duration time.Duration) []speedSample {
+	start := time.Unix(1600000000, 0)
+	var samples []speedSample
+	for d := time.Duration(0); d <= duration; d += 100 * time.Millisecond {
+		samples = append(samples, speedSample{t: start.Add(d), mph: startMPH + testAccelMPH*d.Seconds()})
+	}
+	return samples
+}
+
+// secondsToFeet returns how long a standing start takes to cover feet at
+// testAccelFPS.
+func secondsToFeet(feet float64) float64 {
+	return math.Sqrt(2 * feet / testAccelFPS)
+}
+
+func TestTimeRun(t *testing.T) {
+	bands, err := parseBands("0-30,0-60,50-70,1/4mi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	quarter := secondsToFeet(1320)
+	// The trap speed is the average over the last 66ft.
+	trap := func(rollout float64) float64 {
+		dt := secondsToFeet(rollout+1320) - secondsToFeet(rollout+1320-trapDistance)
+		return trapDistance / dt * 3600 / feetPerMile
+	}
+	cases := []struct {
+		name     string
+		samples  []speedSample
+		rollout  float64
+		launch   bool
+		startMPH float64
+		want     map[string]float64
+		trapMPH  float64
+	}{
+		{
+			name:    "launch",
+			samples: constantAccel(0, 15*time.Second),
+			launch:  true,
+			want: map[string]float64{
+				"0-30":  3,
+				"0-60":  6,
+				"50-70": 2,
+				"1/4mi": quarter,
+			},
+			trapMPH: trap(0),
+		},
+		{
+			// The clock starts after the first foot so standing start
+			// bands are shorter, rolling bands aren't affected.
+			name:    "rollout",
+			samples: constantAccel(0, 15*time.Second),
+			rollout: 1,
+			launch:  true,
+			want: map[string]float64{
+				"0-30":  3 - secondsToFeet(1),
+				"0-60":  6 - secondsToFeet(1),
+				"50-70": 2,
+				"1/4mi": secondsToFeet(1321) - secondsToFeet(1),
+			},
+			trapMPH: trap(1),
+		},
+		{
+			name:     "rolling start",
+			samples:  constantAccel(20, 6*time.Second),
+			rollout:  1,
+			startMPH: 20,
+			want:     map[string]float64{"50-70": 2},
+		},
+		{
+			name:    "short run",
+			samples: constantAccel(0, 4*time.Second),
+			launch:  true,
+			want:    map[string]float64{"0-30": 3},
+		},
+	}
+	for _, c := range cases {
+		run := timeRun(c.samples, len(c.samples)-1, bands, c.rollout)
+		if run.Launch != c.launch || run.StartMPH != c.startMPH {
+			t.Errorf("%s: launch %t from %g mph; expected %t from %g", c.name, run.Launch, run.StartMPH, c.launch, c.startMPH)
+		}
+		if want := c.samples[len(c.samples)-1].mph; run.PeakMPH != want {
+			t.Errorf("%s: peak %g mph; expected %g", c.name, run.PeakMPH, want)
+		}
+		if len(run.Bands) != len(c.want) {
+			t.Errorf("%s: bands = %+v; expected %v", c.name, run.Bands, c.want)
+		}
+		for name, want := range c.want {
+			result, ok := run.band(name)
+			if !ok {
+				t.Errorf("%s: missing band %s", c.name, name)
+				continue
+			}
+			if math.Abs(result.Seconds-want) > 0.01 {
+				t.Errorf("%s: %s = %.3fs; expected %.3fs", c.name, name, result.Seconds, want)
+			}
+		}
+		if result, ok := run.band("1/4mi"); ok && math.Abs(result.TrapMPH-c.trapMPH) > 0.1 {
+			t.Errorf("%s: trap speed %.2f mph; expected %.2f", c.name, result.TrapMPH, c.trapMPH)
+		}
+	}
+}
+
+func TestTimeRunExtrapolatesLaunch(t *testing.T) {
+	// The car was last seen stopped 1s before it started moving at t=0.
+	samples := constantAccel(0, 7*time.Second)[1:]
+	samples = append([]speedSample{{t: samples[0].t.Add(-1100 * time.Millisecond)}}, samples...)
+	bands, err := parseBands("0-60")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := timeRun(samples, len(samples)-1, bands, 0)
+	if want := samples[1].t.Add(-100 * time.Millisecond); !run.Start.Equal(want) {
+		t.Errorf("start = %v; expected %v", run.Start, want)
+	}
+	if result, ok := run.band("0-60"); !ok || math.Abs(result.Seconds-6) > 0.01 {
+		t.Errorf("0-60 = %+v; expected 6s", result)
+	}
+}
+
+func TestPeakAxles(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	at := func(seconds float64) time.Time {
+		return start.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	axles := []axleSample{
+		// Before the run.
+		{t: at(-1), frontTorque: 900, frontRPM: 9000, rearTorque: 900, rearRPM: 9000},
+		{t: at(0), frontTorque: 300, frontRPM: 1000, rearTorque: 400, rearRPM: 1000},
+		{t: at(1), frontTorque: 250, frontRPM: 6000, rearTorque: 350, rearRPM: 6000},
+		{t: at(2), frontTorque: 100, frontRPM: 9000, rearTorque: 200, rearRPM: 9000},
+		// After the run.
+		{t: at(3.5), frontTorque: 900, frontRPM: 9000, rearTorque: 900, rearRPM: 9000},
+	}
+	var run Run
+	peakAxles(&run, axles, at(0), at(3))
+
+	kw := func(torque, rpm float64) float64 {
+		return torque * rpm * 2 * math.Pi / 60 / 1000
+	}
+	want := Run{
+		Front:       AxlePeak{TorqueNm: 300, PowerKW: kw(250, 6000)},
+		Rear:        AxlePeak{TorqueNm: 400, PowerKW: kw(350, 6000)},
+		PeakPowerKW: kw(250, 6000) + kw(350, 6000),
+	}
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"front torque", run.Front.TorqueNm, want.Front.TorqueNm},
+		{"front power", run.Front.PowerKW, want.Front.PowerKW},
+		{"rear torque", run.Rear.TorqueNm, want.Rear.TorqueNm},
+		{"rear power", run.Rear.PowerKW, want.Rear.PowerKW},
+		{"peak power", run.PeakPowerKW, want.PeakPowerKW},
+	} {
+		if math.Abs(c.got-c.want) > 1e-9 {
+			t.Errorf("%s = %g; expected %g", c.name, c.got, c.want)
+		}
+	}
+	if p := axlePowerKW(400, 5000); math.Abs(p-209.4395) > 1e-3 {
+		t.Errorf("axlePowerKW(400, 5000) = %g; expected 209.44", p)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// loopReader reads records from memory, optionally starting over at the end.
+// Looped records are shifted in time so the replay stays continuous.
+type loopReader struct {
+	records []can.Record
+	loop    bool
+
+	i      int
+	offset time.Duration
+}
+
+func (r *loopReader) Read() (can.Record, error) {
+	if r.i >= len(r.records) {
+		if !r.loop || len(r.records) == 0 {
+			return can.Record{}, io.EOF
+		}
+		// Leave the same gap between the last and first frame as between the
+		// first two so the loop doesn't burst.
+		gap := time.Millisecond
+		if len(r.records) > 1 {
+			gap = r.records[1].Time.Sub(r.records[0].Time)
+		}
+		r.offset += r.records[len(r.records)-1].Time.Sub(r.records[0].Time) + gap
+		r.i = 0
+	}
+	record := r.records[r.i]
+	record.Time = record.Time.Add(r.offset)
+	r.i++
+	return record, nil
+}
+
+// replayer paces a recording to its original timing.
+type replayer struct {
+	records []can.Record
+	speed   float64
+	loop    bool
+}
+
+func (p replayer) reader(ctx context.Context) can.RecordReader {
+	return can.NewPacedReader(ctx, &loopReader{records: p.records, loop: p.loop}, p.speed)
+}
+
+// toSocketCAN sends the recording to a SocketCAN interface.
+func (p replayer) toSocketCAN(ctx context.Context, iface string) error {
+	s, err := can.OpenSocketCAN(iface)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	log.Printf("replaying %d frames to %s", len(p.records), iface)
+	reader := p.reader(ctx)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := s.Write(record.Frame); err != nil {
+			return err
+		}
+	}
+}
+
+// ServeHTTP streams the recording as CSV rows like the canbus device. Each
+// request gets its own replay from the start.
+func (p replayer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Printf("replaying %d frames to %s", len(p.records), r.RemoteAddr)
+	w.Header().Set("Content-Type", "text/csv")
+	flusher, _ := w.(http.Flusher)
+	cw := csv.NewWriter(w)
+
+	reader := p.reader(r.Context())
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			return
+		}
+		if err := cw.Write(can.FormatCSV(record.Frame)); err != nil {
+			return
+		}
+		cw.Flush()
+		if cw.Error() != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// replay reads a recording from stdin and re-emits it with its original
+// timing onto a SocketCAN interface or as an HTTP CSV stream.
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	to := fs.String("to", "", "socketcan://<interface> to send the frames to, such as socketcan://vcan0")
+	bind := fs.String("bind", "", "address to serve the frames on as a CSV stream like the canbus device, such as :8080")
+	speed := fs.Float64("speed", 1, "replay speed, 0 for as fast as possible")
+	loop := fs.Bool("loop", false, "start over at the end of the recording")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *to == "" && *bind == "" {
+		fs.Usage()
+		return errors.New("one of -to or -bind is required")
+	}
+	var iface string
+	if *to != "" {
+		u, err := url.Parse(*to)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "socketcan" {
+			return errors.Errorf("unsupported replay target %q", *to)
+		}
+		iface = u.Host
+	}
+
+	reader, _, err := can.OpenRecordReader(os.Stdin)
+	if err != nil {
+		return err
+	}
+	records, err := can.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return errors.New("no records")
+	}
+	p := replayer{records: records, speed: *speed, loop: *loop}
+	return p.run(context.Background(), iface, *bind)
+}
+
+// run replays to the SocketCAN interface and serves the stream on bind, each
+// if set. Once the SocketCAN replay ends or either fails everything is
+// stopped.
+func (p replayer) run(ctx context.Context, iface, bind string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	eg, ctx := errgroup.WithContext(ctx)
+	if iface != "" {
+		eg.Go(func() error {
+			defer cancel()
+			return p.toSocketCAN(ctx, iface)
+		})
+	}
+	if bind != "" {
+		s := http.Server{
+			Addr:    bind,
+			Handler: p,
+			// Cancel the streams on shutdown, otherwise it waits for them.
+			BaseContext: func(net.Listener) context.Context { return ctx },
+		}
+		eg.Go(func() error {
+			log.Printf("serving frames on %s", s.Addr)
+			if err := s.ListenAndServe(); err != http.ErrServerClosed {
+				return errors.Wrap(err, "ListenAndServe")
+			}
+			return nil
+		})
+		eg.Go(func() error {
+			<-ctx.Done()
+			return errors.Wrap(s.Shutdown(context.Background()), "Shutdown")
+		})
+	}
+	return eg.Wait()
+}
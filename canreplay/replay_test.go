@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+)
+
+// streamIDs reads up to n frames from the CSV stream and returns their IDs.
+func streamIDs(t *testing.T, url string, n int) []int {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/csv" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	r := csv.NewReader(resp.Body)
+	var ids []int
+	for len(ids) < n {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		frame, err := can.ParseCSV(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, frame.ID)
+	}
+	return ids
+}
+
+func TestReplayHTTP(t *testing.T) {
+	_, records := testRecords(t)
+
+	ts := httptest.NewServer(replayer{records: records})
+	defer ts.Close()
+	if got, want := streamIDs(t, ts.URL, 100), []int{1, 2, 1, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stream = %v; expected %v", got, want)
+	}
+
+	loop := httptest.NewServer(replayer{records: records, loop: true})
+	defer loop.Close()
+	if got, want := streamIDs(t, loop.URL, 10), []int{1, 2, 1, 1, 1, 2, 1, 1, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("looped stream = %v; expected %v", got, want)
+	}
+
+	// The recording spans 4s so at 20x it takes 200ms.
+	paced := httptest.NewServer(replayer{records: records, speed: 20})
+	defer paced.Close()
+	start := time.Now()
+	if got := streamIDs(t, paced.URL, 100); len(got) != len(records) {
+		t.Errorf("paced stream = %v", got)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("paced stream took %s; expected about 200ms", elapsed)
+	}
+}
+
+func TestLoopReader(t *testing.T) {
+	_, records := testRecords(t)
+	r := &loopReader{records: records, loop: true}
+	var times []time.Duration
+	for i := 0; i < 6; i++ {
+		record, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		times = append(times, record.Time.Sub(records[0].Time))
+	}
+	// The loop starts again after the gap between the first two records.
+	want := []time.Duration{0, 1500 * time.Millisecond, 2 * time.Second, 4 * time.Second, 5500 * time.Millisecond, 7 * time.Second}
+	if !reflect.DeepEqual(times, want) {
+		t.Errorf("looped times = %v; expected %v", times, want)
+	}
+}
+
+func TestReplayStops(t *testing.T) {
+	_, records := testRecords(t)
+	// Slow enough that the stream never finishes by itself.
+	p := replayer{records: records, speed: 0.001, loop: true}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- p.run(ctx, "", addr)
+	}()
+
+	var resp *http.Response
+	for i := 0; ; i++ {
+		resp, err = http.Get("http://" + addr)
+		if err == nil {
+			break
+		} else if i > 100 {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer resp.Body.Close()
+	if _, err := csv.NewReader(resp.Body).Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Shutting down cancels the open stream while it's waiting.
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("run() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run() didn't return after cancelling")
+	}
+
+	// A failed SocketCAN replay stops the server.
+	go func() {
+		done <- p.run(context.Background(), "ricela-missing0", addr)
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("run() with a missing interface succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run() didn't return after the SocketCAN replay failed")
+	}
+}
+
+func TestReplayUsage(t *testing.T) {
+	if err := replay(nil); err == nil {
+		t.Error("replay() without -to or -bind succeeded")
+	}
+}
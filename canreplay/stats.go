@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+)
+
+// stats reads a log from stdin and reports the frame statistics of each ID,
+// by default only the IDs the signal database doesn't decode.
+func stats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	all := fs.Bool("all", false, "include IDs that are decoded by the signal database")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := loadDBC()
+	if err != nil {
+		return err
+	}
+	reader, _, err := can.OpenRecordReader(os.Stdin)
+	if err != nil {
+		return err
+	}
+	s := can.NewStats()
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		s.Add(record)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "id\tmessage\tcount\trate hz\tjitter ms\tdlc\tchanging bits\tchange mask")
+	for _, st := range s.IDs() {
+		name := "-"
+		if msg, ok := db.Messages[st.Key()]; ok {
+			if !*all {
+				continue
+			}
+			name = msg.Name
+		}
+		// The average rate over the whole log is more useful offline than the
+		// smoothed rate.
+		var rate float64
+		if d := st.Last.Sub(st.First); d > 0 {
+			rate = float64(st.Count-1) / d.Seconds()
+		}
+		id := fmt.Sprintf("0x%03X", st.ID)
+		if st.Extended {
+			id = fmt.Sprintf("0x%08X", st.ID)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.1f\t%.2f\t%d\t%d\t%X\n",
+			id, name, st.Count, rate, float64(st.Jitter)/float64(time.Millisecond),
+			st.DLC, st.ChangingBits(), st.ChangeMask)
+	}
+	return w.Flush()
+}
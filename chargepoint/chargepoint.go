@@ -55,6 +55,8 @@ type DeviceData struct {
 type StartSessionRequest struct {
 	DeviceData DeviceData `json:"deviceData"`
 	DeviceID   int64      `json:"deviceId"`
+	// PortNumber is left out to let the station pick a port.
+	PortNumber int `json:"portNumber,omitempty"`
 }
 
 type StopSessionRequest struct {
@@ -247,10 +249,13 @@ func (c *Client) StopSession(ctx context.Context, sessionID, deviceID int64) err
 	return nil
 }
 
-func (c *Client) StartSession(ctx context.Context, deviceID int64) (int64, error) {
+// StartSession starts charging on a station. port is the port number on
+// stations with more than one, 0 lets the station pick.
+func (c *Client) StartSession(ctx context.Context, deviceID int64, port int) (int64, error) {
 	var resp StartSessionResponse
 	if err := c.makeRequest(ctx, AccountEndpoint+SessionStartPath, StartSessionRequest{
-		DeviceID: deviceID,
+		DeviceID:   deviceID,
+		PortNumber: port,
 		DeviceData: DeviceData{
 			Manufacturer:       "unknown",
 			Model:              "unknown",
@@ -0,0 +1,146 @@
+package chargepoint
+
+import (
+	"context"
+	"math"
+)
+
+const StationStatusAvailable = "available"
+
+// BoundingBox is the area of the map to search for stations.
+type BoundingBox struct {
+	NELat float64
+	NELon float64
+	SWLat float64
+	SWLon float64
+}
+
+// BoundsAround returns the bounding box of the circle of radius meters around
+// lat, lon.
+func BoundsAround(lat, lon, radius float64) BoundingBox {
+	const metersPerDegree = 111_320
+	dLat := radius / metersPerDegree
+	dLon := dLat / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	return BoundingBox{
+		NELat: lat + dLat,
+		NELon: lon + dLon,
+		SWLat: lat - dLat,
+		SWLon: lon - dLon,
+	}
+}
+
+// StationFilter restricts the stations returned by StationList. The zero
+// value returns all stations.
+type StationFilter struct {
+	// Available only returns stations with a free port.
+	Available bool
+	// Level2 and DCFast only return stations with those power levels. If
+	// both are set stations with either are returned.
+	Level2 bool
+	DCFast bool
+	// Free only returns stations that don't charge for charging. The map
+	// doesn't return prices so there's no way to filter by a maximum price.
+	Free bool
+}
+
+type StationListFilter struct {
+	StatusAvailable bool `json:"status_available,omitempty"`
+	ConnectorL2     bool `json:"connector_l2,omitempty"`
+	DCFastCharging  bool `json:"dc_fast_charging,omitempty"`
+	PriceFree       bool `json:"price_free,omitempty"`
+}
+
+type StationListRequest struct {
+	StationList struct {
+		NELat           float64           `json:"ne_lat"`
+		NELon           float64           `json:"ne_lon"`
+		SWLat           float64           `json:"sw_lat"`
+		SWLon           float64           `json:"sw_lon"`
+		PageSize        int               `json:"page_size"`
+		SortBy          string            `json:"sort_by"`
+		ReferenceLat    float64           `json:"reference_lat"`
+		ReferenceLon    float64           `json:"reference_lon"`
+		IncludeMapBound bool              `json:"include_map_bound"`
+		Filter          StationListFilter `json:"filter"`
+	} `json:"station_list"`
+}
+
+type PortCount struct {
+	Available int `json:"available"`
+	Total     int `json:"total"`
+}
+
+type Station struct {
+	DeviceID      int64     `json:"device_id"`
+	Lat           float64   `json:"lat"`
+	Lon           float64   `json:"lon"`
+	Name1         string    `json:"name1"`
+	Name2         string    `json:"name2"`
+	Address1      string    `json:"address1"`
+	City          string    `json:"city"`
+	StationStatus string    `json:"station_status"`
+	PortCount     PortCount `json:"port_count"`
+}
+
+// Name is the display name of the station.
+func (s Station) Name() string {
+	if s.Name2 == "" {
+		return s.Name1
+	}
+	return s.Name1 + " " + s.Name2
+}
+
+// Available reports whether the station has a port free. The status is only
+// used when the station doesn't report its ports since it can lag behind them.
+func (s Station) Available() bool {
+	if s.PortCount.Total > 0 {
+		return s.PortCount.Available > 0
+	}
+	return s.StationStatus == StationStatusAvailable
+}
+
+type StationListResponse struct {
+	StationList struct {
+		Stations []Station `json:"stations"`
+	} `json:"station_list"`
+}
+
+// stationPageSize is the number of stations requested, the search area is
+// expected to be small.
+const stationPageSize = 50
+
+// StationList searches for stations within bounds, closest to the reference
+// point first.
+func (c *Client) StationList(ctx context.Context, bounds BoundingBox, refLat, refLon float64, filter StationFilter) ([]Station, error) {
+	var req StationListRequest
+	req.StationList.NELat = bounds.NELat
+	req.StationList.NELon = bounds.NELon
+	req.StationList.SWLat = bounds.SWLat
+	req.StationList.SWLon = bounds.SWLon
+	req.StationList.PageSize = stationPageSize
+	req.StationList.SortBy = "distance"
+	req.StationList.ReferenceLat = refLat
+	req.StationList.ReferenceLon = refLon
+	req.StationList.Filter = StationListFilter{
+		StatusAvailable: filter.Available,
+		ConnectorL2:     filter.Level2,
+		DCFastCharging:  filter.DCFast,
+		PriceFree:       filter.Free,
+	}
+
+	var resp StationListResponse
+	if err := c.makeRequest(ctx, MapProdEndpoint, req, &resp); err != nil {
+		return nil, err
+	}
+
+	// The map includes stations that become busy while it's cached so check
+	// availability again.
+	var stations []Station
+	for _, s := range resp.StationList.Stations {
+		if filter.Available && !s.Available() {
+			continue
+		}
+		stations = append(stations, s)
+	}
+	return stations, nil
+}
@@ -0,0 +1,48 @@
+package chargepoint
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBoundsAround(t *testing.T) {
+	cases := []struct {
+		lat, lon, radius float64
+		want             BoundingBox
+	}{
+		{0, 0, 111320, BoundingBox{NELat: 1, NELon: 1, SWLat: -1, SWLon: -1}},
+		{60, 10, 111320, BoundingBox{NELat: 61, NELon: 12, SWLat: 59, SWLon: 8}},
+		{-33.9, 151.2, 0, BoundingBox{NELat: -33.9, NELon: 151.2, SWLat: -33.9, SWLon: 151.2}},
+		// Longitude degrees are capped near the poles.
+		{90, 0, 1113.2, BoundingBox{NELat: 90.01, NELon: 1, SWLat: 89.99, SWLon: -1}},
+	}
+	for _, c := range cases {
+		out := BoundsAround(c.lat, c.lon, c.radius)
+		got := []float64{out.NELat, out.NELon, out.SWLat, out.SWLon}
+		want := []float64{c.want.NELat, c.want.NELon, c.want.SWLat, c.want.SWLon}
+		for i := range got {
+			if math.Abs(got[i]-want[i]) > 1e-9 {
+				t.Errorf("BoundsAround(%g, %g, %g) = %+v; expected %+v", c.lat, c.lon, c.radius, out, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestStationAvailable(t *testing.T) {
+	cases := []struct {
+		station Station
+		want    bool
+	}{
+		{Station{PortCount: PortCount{Available: 1, Total: 2}}, true},
+		{Station{PortCount: PortCount{Available: 0, Total: 2}}, false},
+		{Station{StationStatus: StationStatusAvailable}, true},
+		{Station{StationStatus: StationStatusAvailable, PortCount: PortCount{Available: 0, Total: 2}}, false},
+		{Station{StationStatus: "in_use"}, false},
+	}
+	for _, c := range cases {
+		if out := c.station.Available(); out != c.want {
+			t.Errorf("%+v.Available() = %t; expected %t", c.station, out, c.want)
+		}
+	}
+}
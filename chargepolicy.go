@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/d4l3k/ricela/notify"
+	"github.com/d4l3k/ricela/policy"
+	"github.com/jsgoecke/tesla"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var policyDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "policy:decisions",
+	Help: "Number of charging policy evaluations by the rule that decided them.",
+}, []string{"decision"})
+
+func newPolicy() (policy.Policy, error) {
+	hours, err := policy.ParseWindows(*policyHours)
+	if err != nil {
+		return policy.Policy{}, err
+	}
+	return policy.Policy{
+		TargetSOC:    *policyTargetSOC,
+		MaxCost:      *policyMaxCost,
+		MaxEnergyKWh: *policyMaxKWh,
+		Hours:        hours,
+	}, nil
+}
+
+// evaluatePolicy checks the charging policy, logging and recording the
+// decision.
+func (r *RiceLa) evaluatePolicy(s policy.State) policy.Decision {
+	d := r.policy.Evaluate(s)
+	log.Printf("charging policy: %s", d)
+	label := d.Rule
+	if !d.Stop {
+		label = "continue"
+	}
+	policyDecisions.WithLabelValues(label).Inc()
+	return d
+}
+
+// policyState is the policy state of a car that's charging.
+func (r *RiceLa) policyState(data VehicleData) policy.State {
+	return policy.State{
+		Time:         time.Now(),
+		BatteryLevel: float64(data.ChargeState.BatteryLevel),
+		EnergyKWh:    data.ChargeState.ChargeEnergyAdded,
+		Cost:         r.sessionCost(),
+	}
+}
+
+func (r *RiceLa) setSessionCost(cost float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.mu.sessionCost = cost
+}
+
+// sessionCost is the cost of the active ChargePoint session as of the last
+// poll, 0 if there isn't one.
+func (r *RiceLa) sessionCost() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.mu.sessionCost
+}
+
+// vehicleCharger starts and stops charging through the Tesla API.
+type vehicleCharger interface {
+	StartCharging() error
+	StopCharging() error
+}
+
+// startState is the policy state used to decide whether to start charging.
+// The energy and cost are from the previous session so only the battery
+// level and time can prevent starting.
+func startState(data VehicleData, now time.Time) policy.State {
+	return policy.State{
+		Time:         now,
+		BatteryLevel: float64(data.ChargeState.BatteryLevel),
+	}
+}
+
+// chargeWait tracks charging that the charging hours held back while the car
+// is plugged in.
+type chargeWait struct {
+	waiting bool
+}
+
+// hold records a decision that stopped or prevented charging. Only the
+// charging hours change while the car is plugged in so charging is only
+// retried for them.
+func (w *chargeWait) hold(d policy.Decision) {
+	if d.Stop && d.Rule == policy.RuleHours {
+		w.waiting = true
+	}
+}
+
+// startCharging starts charging when the charge port opens unless the policy
+// prevents it. Charging held back by the charging hours is retried on each
+// poll until they allow it or the charge port closes.
+func (r *RiceLa) startCharging(ctx context.Context, v vehicleCharger, w *chargeWait, prev, data *VehicleData, now time.Time) error {
+	switch {
+	case prev != nil && !prev.ChargeState.ChargePortDoorOpen && data.ChargeState.ChargePortDoorOpen:
+		if d := r.evaluatePolicy(startState(*data, now)); d.Stop {
+			w.hold(d)
+			return nil
+		}
+		return r.startNearbyCharging(ctx, data.DriveState)
+	case !data.ChargeState.ChargePortDoorOpen, data.ChargeState.ChargingState == StateCharging:
+		w.waiting = false
+	case w.waiting:
+		d := r.policy.Evaluate(startState(*data, now))
+		if d.Stop {
+			w.waiting = d.Rule == policy.RuleHours
+			return nil
+		}
+		w.waiting = false
+		log.Printf("charging hours allow charging at %s, starting", now.Format("15:04"))
+		return r.resumeCharging(ctx, v, data.DriveState)
+	}
+	return nil
+}
+
+// resumeCharging starts charging that was held back. It uses a nearby
+// ChargePoint charger if there is one and otherwise tells the car to start,
+// such as at home where it was stopped through the Tesla API.
+func (r *RiceLa) resumeCharging(ctx context.Context, v vehicleCharger, data tesla.DriveState) error {
+	charger, err := r.nearbyCharger(ctx, data)
+	if err != nil {
+		return err
+	}
+	if charger != nil {
+		if err := charger.Start(ctx, r); err != nil {
+			r.notify(ctx, notify.ChargingFailed, "Charging failed", "Failed to start %s: %v", charger, err)
+			return err
+		}
+		r.notify(ctx, notify.ChargingStarted, "Charging started", "Started charging at %s.", charger)
+		return nil
+	}
+	if err := v.StartCharging(); err != nil {
+		r.notify(ctx, notify.ChargingFailed, "Charging failed", "Failed to start charging: %v", err)
+		return err
+	}
+	r.notify(ctx, notify.ChargingStarted, "Charging started", "Started charging within the charging hours.")
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/d4l3k/ricela/policy"
+)
+
+// fakeVehicle counts the charging commands sent through the Tesla API.
+type fakeVehicle struct {
+	starts, stops int
+}
+
+func (v *fakeVehicle) StartCharging() error {
+	v.starts++
+	return nil
+}
+
+func (v *fakeVehicle) StopCharging() error {
+	v.stops++
+	return nil
+}
+
+func TestStartChargingDeferred(t *testing.T) {
+	r := &RiceLa{
+		chargers: &chargerRegistry{},
+		policy:   policy.Policy{Hours: []policy.Window{{Start: 22, End: 7}}},
+	}
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.Local)
+	state := func(doorOpen bool, charging string) *VehicleData {
+		var data VehicleData
+		data.ChargeState.ChargePortDoorOpen = doorOpen
+		data.ChargeState.ChargingState = charging
+		data.ChargeState.BatteryLevel = 50
+		return &data
+	}
+	closed := state(false, StateDisconnected)
+	pluggedIn := state(true, "Stopped")
+	charging := state(true, StateCharging)
+
+	type poll struct {
+		hour int
+		data *VehicleData
+		// starts is the total number of starts after the poll.
+		starts int
+	}
+	cases := []struct {
+		name  string
+		polls []poll
+	}{
+		{
+			name: "plugged in before the window",
+			polls: []poll{
+				{18, closed, 0},
+				{19, pluggedIn, 0},
+				{21, pluggedIn, 0},
+				{22, pluggedIn, 1},
+				{23, charging, 1},
+				// Charging isn't started again after it's been started
+				// once.
+				{23, pluggedIn, 1},
+			},
+		},
+		{
+			name: "unplugged before the window",
+			polls: []poll{
+				{18, closed, 0},
+				{19, pluggedIn, 0},
+				{20, closed, 0},
+				{22, pluggedIn, 0},
+			},
+		},
+		{
+			name: "plugged in during the window",
+			polls: []poll{
+				{21, closed, 0},
+				// No charger is nearby so the door opening doesn't start
+				// charging through the Tesla API, the car starts itself.
+				{22, pluggedIn, 0},
+				{23, pluggedIn, 0},
+			},
+		},
+	}
+	for _, c := range cases {
+		v := &fakeVehicle{}
+		var wait chargeWait
+		var prev *VehicleData
+		for i, p := range c.polls {
+			now := day.Add(time.Duration(p.hour) * time.Hour)
+			if err := r.startCharging(context.Background(), v, &wait, prev, p.data, now); err != nil {
+				t.Fatalf("%s: poll %d: %v", c.name, i, err)
+			}
+			if v.starts != p.starts {
+				t.Errorf("%s: poll %d at %d:00: %d starts; expected %d", c.name, i, p.hour, v.starts, p.starts)
+			}
+			prev = p.data
+		}
+	}
+}
+
+func TestStartChargingAfterHoursStop(t *testing.T) {
+	r := &RiceLa{
+		chargers: &chargerRegistry{},
+		policy:   policy.Policy{Hours: []policy.Window{{Start: 22, End: 7}}},
+	}
+	v := &fakeVehicle{}
+	var wait chargeWait
+	var data VehicleData
+	data.ChargeState.ChargePortDoorOpen = true
+	data.ChargeState.ChargingState = "Stopped"
+	data.ChargeState.BatteryLevel = 50
+	morning := time.Date(2021, 1, 1, 8, 0, 0, 0, time.Local)
+
+	// Charging was stopped at the end of the window.
+	wait.hold(r.policy.Evaluate(startState(data, morning)))
+	if err := r.startCharging(context.Background(), v, &wait, &data, &data, morning.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if v.starts != 0 {
+		t.Fatalf("started outside of the charging hours")
+	}
+	if err := r.startCharging(context.Background(), v, &wait, &data, &data, morning.Add(14*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if v.starts != 1 {
+		t.Errorf("%d starts once the window opened; expected 1", v.starts)
+	}
+
+	// Other rules can't change while the car is plugged in so they aren't
+	// retried.
+	r.policy.TargetSOC = 40
+	wait = chargeWait{}
+	wait.hold(r.policy.Evaluate(startState(data, morning)))
+	if wait.waiting {
+		t.Errorf("waiting after the target state of charge stopped charging")
+	}
+}
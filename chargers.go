@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/pkg/errors"
+)
+
+const (
+	ProviderChargePoint = "chargepoint"
+
+	// DefaultChargerRadius is how close in meters the car must be to a
+	// charger to start charging if the charger doesn't set a radius.
+	DefaultChargerRadius = 20
+)
+
+// defaultChargers are used until a charger file is written.
+var defaultChargers = []ChargerConfig{
+	{
+		Name:     "chargepoint-1947511",
+		Provider: ProviderChargePoint,
+		DeviceID: 1947511,
+		Lat:      47.630007,
+		Lng:      -122.133969,
+		Enabled:  true,
+	},
+}
+
+// ChargerConfig is a charger from the charger file.
+type ChargerConfig struct {
+	// Name identifies the charger and must be unique.
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	DeviceID int64  `json:"device_id"`
+	// PortID is the port to charge on at stations with more than one, 0
+	// lets the station pick.
+	PortID int     `json:"port_id,omitempty"`
+	Lat    float64 `json:"lat"`
+	Lng    float64 `json:"lng"`
+	// RadiusMeters defaults to DefaultChargerRadius.
+	RadiusMeters float64 `json:"radius_meters,omitempty"`
+	// Enabled defaults to true if it's left out.
+	Enabled bool `json:"enabled"`
+	// IdleGraceMinutes is how long the car can stay plugged in after
+	// charging stops before the station charges idle fees. It defaults to
+	// the -idleGrace flag.
+	IdleGraceMinutes float64 `json:"idle_grace_minutes,omitempty"`
+}
+
+func (c *ChargerConfig) UnmarshalJSON(b []byte) error {
+	type config ChargerConfig
+	out := config{Enabled: true}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return err
+	}
+	*c = ChargerConfig(out)
+	return nil
+}
+
+func (c ChargerConfig) Validate() error {
+	if c.Name == "" || strings.Contains(c.Name, "/") {
+		return errors.Errorf("invalid charger name %q", c.Name)
+	}
+	if c.Provider != ProviderChargePoint {
+		return errors.Errorf("%s: unknown provider %q", c.Name, c.Provider)
+	}
+	if c.DeviceID == 0 {
+		return errors.Errorf("%s: missing device_id", c.Name)
+	}
+	if c.PortID < 0 {
+		return errors.Errorf("%s: invalid port_id %d", c.Name, c.PortID)
+	}
+	if c.Lat < -90 || c.Lat > 90 || c.Lng < -180 || c.Lng > 180 {
+		return errors.Errorf("%s: invalid location %f,%f", c.Name, c.Lat, c.Lng)
+	}
+	if c.RadiusMeters < 0 {
+		return errors.Errorf("%s: invalid radius %f", c.Name, c.RadiusMeters)
+	}
+	if c.IdleGraceMinutes < 0 {
+		return errors.Errorf("%s: invalid idle grace %f", c.Name, c.IdleGraceMinutes)
+	}
+	return nil
+}
+
+// Radius returns the radius in meters, applying the default.
+func (c ChargerConfig) Radius() float64 {
+	if c.RadiusMeters == 0 {
+		return DefaultChargerRadius
+	}
+	return c.RadiusMeters
+}
+
+// IdleGrace returns the idle fee grace period, applying the default.
+func (c ChargerConfig) IdleGrace() time.Duration {
+	if c.IdleGraceMinutes == 0 {
+		return *idleGrace
+	}
+	return time.Duration(c.IdleGraceMinutes * float64(time.Minute))
+}
+
+// Charger returns the Charger for the provider.
+func (c ChargerConfig) Charger() Charger {
+	return ChargePointCharger{
+		DeviceID: c.DeviceID,
+		Port:     c.PortID,
+		LatLng:   s2.LatLngFromDegrees(c.Lat, c.Lng),
+	}
+}
+
+// chargerRegistry holds the chargers from the charger file and reloads them
+// when the file changes.
+type chargerRegistry struct {
+	path string
+	// token must be sent as a bearer token to change the chargers over HTTP.
+	// Changes are rejected if it's empty.
+	token string
+
+	mu       sync.Mutex
+	chargers []ChargerConfig
+	modTime  time.Time
+}
+
+// loadChargerRegistry loads the chargers from path, using defaultChargers if
+// it doesn't exist yet.
+func loadChargerRegistry(path string) (*chargerRegistry, error) {
+	r := &chargerRegistry{
+		path:     path,
+		chargers: defaultChargers,
+	}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload reads the charger file if it has changed since it was last read.
+func (r *chargerRegistry) reload() (bool, error) {
+	info, err := os.Stat(r.path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if info.ModTime().Equal(r.modTime) {
+		return false, nil
+	}
+	body, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return false, err
+	}
+	// Only report each broken version of the file once.
+	r.modTime = info.ModTime()
+	var chargers []ChargerConfig
+	if err := json.Unmarshal(body, &chargers); err != nil {
+		return false, errors.Wrapf(err, "parsing %s", r.path)
+	}
+	if err := validateChargers(chargers); err != nil {
+		return false, errors.Wrap(err, r.path)
+	}
+	r.chargers = chargers
+	return true, nil
+}
+
+func validateChargers(chargers []ChargerConfig) error {
+	names := map[string]bool{}
+	for _, c := range chargers {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		if names[c.Name] {
+			return errors.Errorf("duplicate charger %q", c.Name)
+		}
+		names[c.Name] = true
+	}
+	return nil
+}
+
+// Watch polls the charger file for changes. Invalid changes are logged and
+// the previous chargers are kept.
+func (r *chargerRegistry) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		changed, err := r.reload()
+		if err != nil {
+			log.Printf("failed to reload chargers: %+v", err)
+		} else if changed {
+			log.Printf("reloaded chargers from %s", r.path)
+		}
+	}
+}
+
+// save writes the chargers to the charger file. r.mu must be held.
+func (r *chargerRegistry) save(chargers []ChargerConfig) error {
+	body, err := json.MarshalIndent(chargers, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it so the file is never seen half
+	// written.
+	tmp, err := ioutil.TempFile(filepath.Dir(r.path), filepath.Base(r.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(body, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return err
+	}
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	r.chargers = chargers
+	r.modTime = info.ModTime()
+	return nil
+}
+
+// Chargers returns all the configured chargers.
+func (r *chargerRegistry) Chargers() []ChargerConfig {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ChargerConfig(nil), r.chargers...)
+}
+
+// Nearby returns the enabled chargers within their radius of latlng, closest
+// first.
+func (r *chargerRegistry) Nearby(latlng s2.LatLng) []Charger {
+	type nearby struct {
+		charger  Charger
+		distance float64
+	}
+	var found []nearby
+	for _, c := range r.Chargers() {
+		if !c.Enabled {
+			continue
+		}
+		charger := c.Charger()
+		if d := charger.DistanceInMeters(latlng); d < c.Radius() {
+			found = append(found, nearby{charger, d})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].distance < found[j].distance
+	})
+	chargers := make([]Charger, len(found))
+	for i, n := range found {
+		chargers[i] = n.charger
+	}
+	return chargers
+}
+
+// IdleGrace returns the idle fee grace period of the ChargePoint station,
+// which may not be a configured charger.
+func (r *chargerRegistry) IdleGrace(deviceID int64) time.Duration {
+	for _, c := range r.Chargers() {
+		if c.Provider == ProviderChargePoint && c.DeviceID == deviceID {
+			return c.IdleGrace()
+		}
+	}
+	return *idleGrace
+}
+
+// invalidChargerError is returned by Put for invalid chargers.
+type invalidChargerError struct {
+	error
+}
+
+// Put adds a charger or replaces the one with the same name.
+func (r *chargerRegistry) Put(c ChargerConfig) error {
+	if err := c.Validate(); err != nil {
+		return invalidChargerError{err}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	chargers := make([]ChargerConfig, 0, len(r.chargers)+1)
+	replaced := false
+	for _, existing := range r.chargers {
+		if existing.Name == c.Name {
+			existing = c
+			replaced = true
+		}
+		chargers = append(chargers, existing)
+	}
+	if !replaced {
+		chargers = append(chargers, c)
+	}
+	return r.save(chargers)
+}
+
+// Delete removes the named charger and reports whether it existed.
+func (r *chargerRegistry) Delete(name string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var chargers []ChargerConfig
+	for _, existing := range r.chargers {
+		if existing.Name != name {
+			chargers = append(chargers, existing)
+		}
+	}
+	if len(chargers) == len(r.chargers) {
+		return false, nil
+	}
+	if chargers == nil {
+		chargers = []ChargerConfig{}
+	}
+	return true, r.save(chargers)
+}
+
+// authorized reports whether the request has the token needed to change the
+// chargers.
+func (r *chargerRegistry) authorized(req *http.Request) bool {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return r.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(r.token)) == 1
+}
+
+// ServeHTTP lists the chargers on GET /chargers, adds or replaces a charger
+// with POST /chargers and removes one with DELETE /chargers/<name>. Changes
+// need the token as a bearer token since chargers are started automatically.
+func (r *chargerRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, "/chargers"), "/")
+	if req.Method != http.MethodGet && !r.authorized(req) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case req.Method == http.MethodGet && name == "":
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(r.Chargers()); err != nil {
+			log.Printf("failed to write chargers: %+v", err)
+		}
+
+	case req.Method == http.MethodPost && name == "":
+		var c ChargerConfig
+		if err := json.NewDecoder(req.Body).Decode(&c); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := r.Put(c); err != nil {
+			status := http.StatusInternalServerError
+			if _, ok := err.(invalidChargerError); ok {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c)
+
+	case req.Method == http.MethodDelete && name != "":
+		found, err := r.Delete(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.NotFound(w, req)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
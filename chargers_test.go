@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func tempChargersFile(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "chargers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "chargers.json"), func() { os.RemoveAll(dir) }
+}
+
+// writeChargers writes the file and bumps its modification time so the change
+// is seen even on file systems with coarse timestamps.
+func writeChargers(t *testing.T, path, body string, modTime time.Time) {
+	if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func chargerNames(chargers []ChargerConfig) []string {
+	var names []string
+	for _, c := range chargers {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func TestChargerRegistryReload(t *testing.T) {
+	path, cleanup := tempChargersFile(t)
+	defer cleanup()
+
+	r, err := loadChargerRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := chargerNames(r.Chargers()); !reflect.DeepEqual(got, chargerNames(defaultChargers)) {
+		t.Fatalf("missing file: chargers = %v; expected the defaults", got)
+	}
+
+	modTime := time.Now().Add(-time.Hour)
+	writeChargers(t, path, `[{"name": "home", "provider": "chargepoint", "device_id": 1, "port_id": 2, "lat": 47, "lng": -122}]`, modTime)
+	if changed, err := r.reload(); err != nil || !changed {
+		t.Fatalf("reload() = %t, %v; expected a change", changed, err)
+	}
+	chargers := r.Chargers()
+	if got := chargerNames(chargers); !reflect.DeepEqual(got, []string{"home"}) {
+		t.Fatalf("chargers = %v", got)
+	}
+	if !chargers[0].Enabled || chargers[0].Radius() != DefaultChargerRadius {
+		t.Errorf("defaults not applied: %+v", chargers[0])
+	}
+	if c, ok := chargers[0].Charger().(ChargePointCharger); !ok || c.DeviceID != 1 || c.Port != 2 {
+		t.Errorf("Charger() = %+v; expected device 1 port 2", chargers[0].Charger())
+	}
+	if changed, err := r.reload(); err != nil || changed {
+		t.Errorf("reload() without changes = %t, %v", changed, err)
+	}
+
+	// Invalid files are reported once and the previous chargers are kept.
+	for i, body := range []string{
+		`not json`,
+		`[{"name": "a", "provider": "chargepoint", "device_id": 1}, {"name": "a", "provider": "chargepoint", "device_id": 2}]`,
+		`[{"name": "a", "provider": "tesla", "device_id": 1}]`,
+		`[{"name": "a", "provider": "chargepoint", "device_id": 1, "port_id": -1}]`,
+	} {
+		modTime = modTime.Add(time.Minute)
+		writeChargers(t, path, body, modTime)
+		if _, err := r.reload(); err == nil {
+			t.Errorf("%d: reload() of invalid file succeeded", i)
+		}
+		if _, err := r.reload(); err != nil {
+			t.Errorf("%d: invalid file reported twice: %v", i, err)
+		}
+		if got := chargerNames(r.Chargers()); !reflect.DeepEqual(got, []string{"home"}) {
+			t.Errorf("%d: chargers = %v after invalid file", i, got)
+		}
+	}
+}
+
+func TestChargerRegistryPutDelete(t *testing.T) {
+	path, cleanup := tempChargersFile(t)
+	defer cleanup()
+
+	r, err := loadChargerRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := ChargerConfig{Name: "work", Provider: ProviderChargePoint, DeviceID: 2, Lat: 1, Lng: 2, Enabled: true}
+	if err := r.Put(work); err != nil {
+		t.Fatal(err)
+	}
+	work.DeviceID = 3
+	if err := r.Put(work); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Put(ChargerConfig{Name: "bad"}); err == nil {
+		t.Error("Put of an invalid charger succeeded")
+	}
+
+	// The changes are saved and loaded again.
+	loaded, err := loadChargerRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(append([]ChargerConfig(nil), defaultChargers...), work)
+	if got := loaded.Chargers(); !reflect.DeepEqual(got, want) {
+		t.Errorf("saved chargers = %+v; expected %+v", got, want)
+	}
+
+	if found, err := r.Delete("work"); err != nil || !found {
+		t.Fatalf("Delete(work) = %t, %v", found, err)
+	}
+	if found, err := r.Delete("work"); err != nil || found {
+		t.Errorf("Delete(work) again = %t, %v", found, err)
+	}
+	if found, err := r.Delete(defaultChargers[0].Name); err != nil || !found {
+		t.Fatalf("Delete(default) = %t, %v", found, err)
+	}
+	loaded, err = loadChargerRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Chargers(); len(got) != 0 {
+		t.Errorf("saved chargers after deleting all = %+v", got)
+	}
+}
+
+func TestChargerRegistryHTTP(t *testing.T) {
+	path, cleanup := tempChargersFile(t)
+	defer cleanup()
+
+	r, err := loadChargerRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.token = "secret"
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	do := func(method, url, token, body string) int {
+		req, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	const charger = `{"name": "work", "provider": "chargepoint", "device_id": 2, "lat": 1, "lng": 2}`
+	cases := []struct {
+		method, url, token, body string
+		want                     int
+	}{
+		{"POST", "/chargers", "", charger, http.StatusUnauthorized},
+		{"POST", "/chargers", "wrong", charger, http.StatusUnauthorized},
+		{"DELETE", "/chargers/work", "", "", http.StatusUnauthorized},
+		{"POST", "/chargers", "secret", `{`, http.StatusBadRequest},
+		{"POST", "/chargers", "secret", `{"name": "work"}`, http.StatusBadRequest},
+		{"POST", "/chargers", "secret", charger, http.StatusOK},
+		{"DELETE", "/chargers/nope", "secret", "", http.StatusNotFound},
+		{"PUT", "/chargers", "secret", charger, http.StatusMethodNotAllowed},
+		{"DELETE", "/chargers/" + defaultChargers[0].Name, "secret", "", http.StatusNoContent},
+	}
+	for _, c := range cases {
+		if got := do(c.method, c.url, c.token, c.body); got != c.want {
+			t.Errorf("%s %s token %q = %d; expected %d", c.method, c.url, c.token, got, c.want)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/chargers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var chargers []ChargerConfig
+	if err := json.NewDecoder(resp.Body).Decode(&chargers); err != nil {
+		t.Fatal(err)
+	}
+	if got := chargerNames(chargers); !reflect.DeepEqual(got, []string{"work"}) {
+		t.Errorf("GET /chargers = %v", got)
+	}
+
+	// Changes are rejected without a configured token.
+	r.token = ""
+	if got := do("DELETE", "/chargers/work", "", ""); got != http.StatusUnauthorized {
+		t.Errorf("DELETE without a configured token = %d", got)
+	}
+}
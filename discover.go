@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/d4l3k/ricela/chargepoint"
+	"github.com/golang/geo/s2"
+	"github.com/pkg/errors"
+)
+
+// stationDiscovery finds ChargePoint stations near the car that aren't in the
+// charger registry. Only stations matching the allowlist are started.
+type stationDiscovery struct {
+	allow  *regexp.Regexp
+	radius float64
+	filter chargepoint.StationFilter
+}
+
+// newStationDiscovery returns nil if allow is empty, which disables
+// discovery. level is "l2", "dc" or empty for any power level.
+func newStationDiscovery(allow string, radius float64, level string, free bool) (*stationDiscovery, error) {
+	if allow == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(allow)
+	if err != nil {
+		return nil, errors.Wrap(err, "discoverAllow")
+	}
+	d := &stationDiscovery{
+		allow:  re,
+		radius: radius,
+		filter: chargepoint.StationFilter{
+			Available: true,
+			Free:      free,
+		},
+	}
+	switch level {
+	case "":
+	case "l2":
+		d.filter.Level2 = true
+	case "dc":
+		d.filter.DCFast = true
+	default:
+		return nil, errors.Errorf("unknown station level %q", level)
+	}
+	return d, nil
+}
+
+// allowed reports whether the station's name, address or device ID matches
+// the allowlist.
+func (d *stationDiscovery) allowed(s chargepoint.Station) bool {
+	return d.allow.MatchString(s.Name()) ||
+		d.allow.MatchString(s.Address1) ||
+		d.allow.MatchString(strconv.FormatInt(s.DeviceID, 10))
+}
+
+// stationCandidate is an allowed station within the discovery radius.
+type stationCandidate struct {
+	charger  ChargePointCharger
+	station  chargepoint.Station
+	distance float64
+}
+
+// candidates returns the allowed stations within the discovery radius of
+// latlng, closest first. Sessions are started on a station and ChargePoint
+// assigns the port so stations at the same distance, such as the ports of a
+// dual port station, are ordered by the number of available ports.
+func (d *stationDiscovery) candidates(stations []chargepoint.Station, latlng s2.LatLng) []stationCandidate {
+	var candidates []stationCandidate
+	for _, s := range stations {
+		charger := ChargePointCharger{
+			DeviceID: s.DeviceID,
+			LatLng:   s2.LatLngFromDegrees(s.Lat, s.Lon),
+		}
+		distance := charger.DistanceInMeters(latlng)
+		if distance > d.radius {
+			continue
+		}
+		if !d.allowed(s) {
+			log.Printf("skipping station %d %q, not in the allowlist", s.DeviceID, s.Name())
+			continue
+		}
+		candidates = append(candidates, stationCandidate{charger, s, distance})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		return a.station.PortCount.Available > b.station.PortCount.Available
+	})
+	return candidates
+}
+
+// discoverCharger returns the closest available allowed station within the
+// discovery radius or nil if there isn't one.
+func (r *RiceLa) discoverCharger(ctx context.Context, latlng s2.LatLng) (Charger, error) {
+	d := r.discovery
+	if d == nil {
+		return nil, nil
+	}
+	lat, lng := latlng.Lat.Degrees(), latlng.Lng.Degrees()
+	stations, err := r.chargepoint.StationList(ctx, chargepoint.BoundsAround(lat, lng, d.radius), lat, lng, d.filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "searching for stations")
+	}
+
+	candidates := d.candidates(stations, latlng)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	c := candidates[0]
+	log.Printf("discovered station %d %q %.0fm away with %d/%d ports available", c.station.DeviceID, c.station.Name(), c.distance, c.station.PortCount.Available, c.station.PortCount.Total)
+	return c.charger, nil
+}
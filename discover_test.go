@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/d4l3k/ricela/chargepoint"
+	"github.com/golang/geo/s2"
+)
+
+func TestStationDiscoveryAllowed(t *testing.T) {
+	d, err := newStationDiscovery(`^Home|Main St|^12345$`, 50, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		station chargepoint.Station
+		want    bool
+	}{
+		{chargepoint.Station{Name1: "Home", Name2: "Garage"}, true},
+		{chargepoint.Station{Name1: "Work", Address1: "1 Main St"}, true},
+		{chargepoint.Station{Name1: "Work", DeviceID: 12345}, true},
+		{chargepoint.Station{Name1: "Work", DeviceID: 123456}, false},
+		{chargepoint.Station{Name1: "My Home"}, false},
+	}
+	for _, c := range cases {
+		if out := d.allowed(c.station); out != c.want {
+			t.Errorf("allowed(%+v) = %t; expected %t", c.station, out, c.want)
+		}
+	}
+
+	if d, err := newStationDiscovery("", 50, "", false); d != nil || err != nil {
+		t.Errorf("newStationDiscovery with no allowlist = %v, %v; expected nil", d, err)
+	}
+	if _, err := newStationDiscovery("(", 50, "", false); err == nil {
+		t.Error("expected error for invalid allowlist")
+	}
+	if _, err := newStationDiscovery(".", 50, "ac", false); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+func TestStationDiscoveryCandidates(t *testing.T) {
+	d, err := newStationDiscovery(`^Allowed`, 100, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const lat, lon = 37.7749, -122.4194
+	// 0.0001 degrees of latitude is about 11 meters.
+	stations := []chargepoint.Station{
+		{DeviceID: 1, Name1: "Allowed far", Lat: lat + 0.0005, Lon: lon},
+		{DeviceID: 2, Name1: "Allowed out of range", Lat: lat + 0.002, Lon: lon},
+		{DeviceID: 3, Name1: "Blocked closest", Lat: lat, Lon: lon},
+		{DeviceID: 4, Name1: "Allowed near", Lat: lat + 0.0002, Lon: lon, PortCount: chargepoint.PortCount{Available: 1, Total: 2}},
+		{DeviceID: 5, Name1: "Allowed near", Lat: lat + 0.0002, Lon: lon, PortCount: chargepoint.PortCount{Available: 2, Total: 2}},
+	}
+	var ids []int64
+	for _, c := range d.candidates(stations, s2.LatLngFromDegrees(lat, lon)) {
+		ids = append(ids, c.station.DeviceID)
+	}
+	if want := []int64{5, 4, 1}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("candidates = %v; expected %v", ids, want)
+	}
+
+	if out := d.candidates(nil, s2.LatLngFromDegrees(lat, lon)); len(out) != 0 {
+		t.Errorf("candidates(nil) = %+v; expected none", out)
+	}
+}
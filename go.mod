@@ -16,7 +16,7 @@ require (
 	github.com/prometheus/procfs v0.3.0 // indirect
 	github.com/ssimunic/gosensors v0.0.0-20170414000417-e7ab9a4e799b
 	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a
-	golang.org/x/sys v0.0.0-20210124154548-22da62e12c0c // indirect
+	golang.org/x/sys v0.0.0-20210124154548-22da62e12c0c
 	golang.org/x/tools v0.0.0-20200326210457-5d86d385bf88 // indirect
 	google.golang.org/protobuf v1.25.0 // indirect
 )
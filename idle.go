@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/d4l3k/ricela/notify"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	idleSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "chargepoint:idle_seconds",
+		Help: "Seconds the car has been plugged in since the longest idle session stopped charging.",
+	})
+	idleEscalations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chargepoint:idle_escalations",
+		Help: "Number of sessions still plugged in after their idle fee grace period.",
+	})
+)
+
+// idleSession is a ChargePoint session that has stopped charging while the
+// car may still be plugged in.
+type idleSession struct {
+	SessionID int64
+	DeviceID  int64
+	Stopped   time.Time
+	// Grace is how long the station allows the car to stay plugged in
+	// before charging idle fees.
+	Grace     time.Duration
+	Escalated bool
+}
+
+// idleTracker tracks sessions from when they stop charging until the car is
+// unplugged.
+type idleTracker struct {
+	mu       sync.Mutex
+	sessions map[int64]*idleSession
+}
+
+func newIdleTracker() *idleTracker {
+	return &idleTracker{sessions: map[int64]*idleSession{}}
+}
+
+// Track records that a session stopped charging at now. Sessions that are
+// already tracked keep their original time.
+func (t *idleTracker) Track(sessionID, deviceID int64, now time.Time, grace time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.sessions[sessionID]; ok {
+		return
+	}
+	t.sessions[sessionID] = &idleSession{
+		SessionID: sessionID,
+		DeviceID:  deviceID,
+		Stopped:   now,
+		Grace:     grace,
+	}
+}
+
+// Overdue returns the sessions that have passed their grace period since the
+// last call and updates the idle metric.
+func (t *idleTracker) Overdue(now time.Time) []idleSession {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var overdue []idleSession
+	var longest time.Duration
+	for _, s := range t.sessions {
+		idle := now.Sub(s.Stopped)
+		if idle > longest {
+			longest = idle
+		}
+		if !s.Escalated && idle >= s.Grace {
+			s.Escalated = true
+			overdue = append(overdue, *s)
+		}
+	}
+	idleSeconds.Set(longest.Seconds())
+	return overdue
+}
+
+// Reset forgets all sessions once the car is unplugged.
+func (t *idleTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sessions = map[int64]*idleSession{}
+	idleSeconds.Set(0)
+}
+
+// checkIdle escalates sessions the car is still plugged into after their
+// idle fee grace period.
+func (r *RiceLa) checkIdle(ctx context.Context, data VehicleData) {
+	if data.ChargeState.ChargingState == StateDisconnected {
+		r.idle.Reset()
+		return
+	}
+	now := time.Now()
+	for _, s := range r.idle.Overdue(now) {
+		idle := now.Sub(s.Stopped).Round(time.Second)
+		log.Printf("car is still plugged into ChargePoint station %d %s after session %d stopped, idle fees may be accruing",
+			s.DeviceID, idle, s.SessionID)
+		idleEscalations.Inc()
+		r.notify(ctx, notify.IdleFee, "Idle fees", "The car is still plugged into ChargePoint station %d %s after charging stopped, idle fees may be accruing.",
+			s.DeviceID, idle)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIdleTrackerOverdue(t *testing.T) {
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := newIdleTracker()
+	tracker.Track(1, 100, start, 10*time.Minute)
+	tracker.Track(2, 200, start.Add(5*time.Minute), 30*time.Minute)
+	// Tracking again keeps the original time.
+	tracker.Track(1, 100, start.Add(time.Hour), 10*time.Minute)
+
+	cases := []struct {
+		after time.Duration
+		want  []int64
+	}{
+		{0, nil},
+		{9 * time.Minute, nil},
+		{10 * time.Minute, []int64{1}},
+		// Each session is only escalated once.
+		{20 * time.Minute, nil},
+		{35 * time.Minute, []int64{2}},
+		{time.Hour, nil},
+	}
+	for _, c := range cases {
+		var got []int64
+		for _, s := range tracker.Overdue(start.Add(c.after)) {
+			got = append(got, s.SessionID)
+		}
+		if len(got) != len(c.want) || (len(got) > 0 && got[0] != c.want[0]) {
+			t.Errorf("Overdue(+%s) = %v; expected %v", c.after, got, c.want)
+		}
+	}
+}
+
+func TestCheckIdleReset(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	r := &RiceLa{idle: newIdleTracker()}
+	r.idle.Track(1, 100, start, 10*time.Minute)
+
+	var data VehicleData
+	data.ChargeState.ChargingState = "Stopped"
+	r.checkIdle(context.Background(), data)
+	if s := r.idle.Overdue(time.Now()); len(s) != 0 {
+		t.Fatalf("session wasn't escalated by checkIdle: %+v", s)
+	}
+
+	data.ChargeState.ChargingState = StateDisconnected
+	r.checkIdle(context.Background(), data)
+	// A new session with the same ID after unplugging is tracked from
+	// scratch.
+	r.idle.Track(1, 100, time.Now(), 10*time.Minute)
+	if s := r.idle.Overdue(time.Now()); len(s) != 0 {
+		t.Errorf("sessions weren't reset on disconnect: %+v", s)
+	}
+	if s := r.idle.Overdue(time.Now().Add(10 * time.Minute)); len(s) != 1 {
+		t.Errorf("new session wasn't escalated after its grace period: %+v", s)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/d4l3k/ricela/can"
+	"github.com/pkg/errors"
+)
+
+// busConfig is a frame source given with -bus.
+type busConfig struct {
+	Name string
+	URI  string
+	// DBC is the signal database for the bus, the -dbc database is used if
+	// it's empty.
+	DBC string
+}
+
+// busFlags collects repeated -bus flags of the form name=uri[,dbc=path].
+type busFlags []busConfig
+
+func (f *busFlags) String() string {
+	var buses []string
+	for _, b := range *f {
+		bus := b.Name + "=" + b.URI
+		if b.DBC != "" {
+			bus += ",dbc=" + b.DBC
+		}
+		buses = append(buses, bus)
+	}
+	return strings.Join(buses, " ")
+}
+
+func (f *busFlags) Set(value string) error {
+	parts := strings.Split(value, ",")
+	nameURI := strings.SplitN(parts[0], "=", 2)
+	if len(nameURI) != 2 || nameURI[0] == "" || nameURI[1] == "" {
+		return errors.Errorf("invalid bus %q, expected name=uri[,dbc=path]", value)
+	}
+	b := busConfig{Name: nameURI[0], URI: nameURI[1]}
+	for _, opt := range parts[1:] {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return errors.Errorf("invalid bus option %q", opt)
+		}
+		switch kv[0] {
+		case "dbc":
+			b.DBC = kv[1]
+		default:
+			return errors.Errorf("unknown bus option %q", kv[0])
+		}
+	}
+	for _, existing := range *f {
+		if existing.Name == b.Name {
+			return errors.Errorf("duplicate bus %q", b.Name)
+		}
+	}
+	*f = append(*f, b)
+	return nil
+}
+
+// bus is a frame source along with the signal database and statistics for
+// the frames on it.
+type bus struct {
+	name  string
+	uri   string
+	db    *can.DBC
+	stats *can.Stats
+	// drive is set on the bus whose gear and drive signals are used for
+	// segments and trips.
+	drive bool
+}
+
+// openBuses loads the signal databases for the configured buses. Without any
+// -bus flags there's a single unnamed bus reading from -canaddr. driveBus
+// names the drive bus, the first bus is used if it's empty.
+func openBuses(configs busFlags, defaultAddr, driveBus string, defaultDB *can.DBC) ([]*bus, error) {
+	if len(configs) == 0 {
+		configs = busFlags{{URI: defaultAddr}}
+	}
+	if driveBus == "" {
+		driveBus = configs[0].Name
+	}
+	found := false
+	var buses []*bus
+	for _, c := range configs {
+		b := &bus{
+			name:  c.Name,
+			uri:   c.URI,
+			db:    defaultDB,
+			stats: can.NewStats(),
+			drive: c.Name == driveBus,
+		}
+		found = found || b.drive
+		if c.DBC != "" {
+			db, err := can.LoadDBC(c.DBC)
+			if err != nil {
+				return nil, errors.Wrapf(err, "bus %s", c.Name)
+			}
+			b.db = db
+		}
+		buses = append(buses, b)
+	}
+	if !found {
+		return nil, errors.Errorf("unknown drive bus %q", driveBus)
+	}
+	return buses, nil
+}
@@ -2,18 +2,21 @@ package main
 
 import (
 	"context"
-	"encoding/csv"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/alecthomas/units"
 	"github.com/d4l3k/ricela/can"
 	"github.com/d4l3k/ricela/sysmetrics"
+	"github.com/d4l3k/ricela/trip"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -22,12 +25,26 @@ import (
 )
 
 var (
-	canAddr        = flag.String("canaddr", "http://192.168.123.10", "address of the canbus device")
+	canAddr        = flag.String("canaddr", "http://192.168.123.10", "frame source, http:// for the canbus device or socketcan://<interface>, ignored if -bus is set")
 	bind           = flag.String("bind", ":2112", "address to bind the http server to")
 	metricPollTime = flag.Duration("metricPollTime", 15*time.Second, "time to poll system metrics")
-	logFile        = flag.String("logfile", "log.json", "file to launch data to")
+	logDir         = flag.String("logdir", "logs", "directory to write a log file per drive to")
+	logFile        = flag.String("logfile", "", "deprecated, sets -logdir to the file's directory and -logformat from its extension unless they're set")
+	logFormat      = flag.String("logformat", string(can.FormatBinary), "format of the log files, see canreplay convert")
+	logMaxSize     = flag.String("logmaxsize", "20GB", "maximum total size of the log files before the oldest are deleted")
+	logMaxAge      = flag.Duration("logmaxage", 90*24*time.Hour, "maximum age of the log files before they're deleted, 0 to keep forever")
+	bitrate        = flag.Float64("bitrate", 500000, "bitrate of the bus in bits per second, used to estimate the bus load")
+	tripFile       = flag.String("tripfile", "trips.jsonl", "file to append trip summaries to")
+	dbcFile        = flag.String("dbc", "", "DBC file with the signal definitions, defaults to the built-in database")
+	driveBus       = flag.String("drivebus", "", "name of the bus whose gear and drive signals start and end drive segments and trips, defaults to the first -bus")
+	wsOrigins      = flag.String("wsorigins", "", "comma separated browser origins such as https://example.com that may open /ws streams, in addition to pages served from -bind")
+	busConfigs     busFlags
 )
 
+func init() {
+	flag.Var(&busConfigs, "bus", "frame source for a named bus as name=uri[,dbc=path], may be repeated")
+}
+
 func main() {
 	if err := run(); err != nil {
 		log.Fatalf("%+v", err)
@@ -36,44 +53,190 @@ func main() {
 
 var counters = map[string]prometheus.Gauge{}
 
-func set(name string, val float64) {
+func set(name, help string, val float64) {
 	counter, ok := counters[name]
 	if !ok {
 		counter = promauto.NewGauge(prometheus.GaugeOpts{
 			Name: "canbus:" + name,
+			Help: help,
 		})
 		counters[name] = counter
 	}
 	counter.Set(val)
 }
 
+var signalCounters = map[string]*prometheus.GaugeVec{}
+
+// setSignal sets a gauge for a decoded signal labeled by the bus it was
+// received on.
+func setSignal(bus, name, help string, val float64) {
+	counter, ok := signalCounters[name]
+	if !ok {
+		counter = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "canbus:" + name,
+			Help: help,
+		}, []string{"bus"})
+		signalCounters[name] = counter
+	}
+	counter.WithLabelValues(bus).Set(val)
+}
+
+var stateCounters = map[string]*prometheus.GaugeVec{}
+
+// setState exports an enumerated signal as a state set with one series per
+// named state, set to 1 for the current state and 0 for the others.
+func setState(bus string, signal *can.Signal, val float64) {
+	name := signal.Name
+	counter, ok := stateCounters[name]
+	if !ok {
+		counter = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "canbus:" + name + "_state",
+			Help: signal.Help(),
+		}, []string{"bus", "state"})
+		stateCounters[name] = counter
+	}
+	current, _ := signal.Label(val)
+	for _, label := range signal.Values {
+		state := 0.0
+		if label == current {
+			state = 1
+		}
+		counter.WithLabelValues(bus, label).Set(state)
+	}
+}
+
+var indexedCounters = map[string]*prometheus.GaugeVec{}
+
+// setIndexed sets a gauge for a multiplexed signal group such as the per
+// brick voltages, labeled by the index within the group.
+func setIndexed(bus, name, help string, index int, val float64) {
+	counter, ok := indexedCounters[name]
+	if !ok {
+		counter = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "canbus:" + name,
+			Help: help,
+		}, []string{"bus", "index"})
+		indexedCounters[name] = counter
+	}
+	counter.WithLabelValues(bus, strconv.Itoa(index)).Set(val)
+}
+
+// setTrip exports the summary of the latest trip.
+func setTrip(t trip.Trip) {
+	set("trip:latest:start_time_seconds", "Start time of the latest trip.", float64(t.Start.Unix()))
+	set("trip:latest:duration_seconds", "Duration of the latest trip.", t.Duration().Seconds())
+	set("trip:latest:distance_miles", "Distance driven on the latest trip.", t.DistanceMiles)
+	set("trip:latest:energy_used_kwh", "Net battery energy used on the latest trip.", t.EnergyUsedKWh)
+	set("trip:latest:regen_kwh", "Energy recovered by regenerative braking on the latest trip.", t.RegenKWh)
+	set("trip:latest:max_speed_mph", "Maximum speed on the latest trip.", t.MaxSpeedMPH)
+	set("trip:latest:max_power_kw", "Maximum drive unit power on the latest trip.", t.MaxPowerKW)
+	set("trip:latest:wh_per_mile", "Average efficiency of the latest trip.", t.WhPerMile())
+	set("trip:latest:elevation_change_m", "Elevation change over the latest trip.", t.ElevationChangeM)
+}
+
 func run() error {
 	flag.Parse()
 	log.SetFlags(log.Flags() | log.Lshortfile)
 
-	eg, ctx := errgroup.WithContext(context.Background())
+	if *logFile != "" {
+		set := map[string]bool{}
+		flag.Visit(func(f *flag.Flag) {
+			set[f.Name] = true
+		})
+		dir, format := logFileSegments(*logFile)
+		if !set["logdir"] {
+			*logDir = dir
+		}
+		if !set["logformat"] {
+			*logFormat = string(format)
+		}
+		log.Printf("-logfile is deprecated, logging each drive to %s in %s format, use -logdir and -logformat instead", *logDir, *logFormat)
+	}
 
-	eg.Go(func() error {
-		for {
-			if err := processCan(ctx); err != nil {
-				log.Printf("failed to process can: %+v", err)
-			}
+	db := can.DefaultDBC
+	if *dbcFile != "" {
+		var err error
+		db, err = can.LoadDBC(*dbcFile)
+		if err != nil {
+			return err
+		}
+	}
+	buses, err := openBuses(busConfigs, *canAddr, *driveBus, db)
+	if err != nil {
+		return err
+	}
 
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.NewTimer(10 * time.Second).C:
+	maxSize, err := units.ParseMetricBytes(*logMaxSize)
+	if err != nil {
+		return errors.Wrap(err, "logmaxsize")
+	}
+	segments, err := newSegmentLog(*logDir, can.Format(*logFormat), maxSize, *logMaxAge)
+	if err != nil {
+		return err
+	}
+	if err := segments.Prune(time.Now()); err != nil {
+		return err
+	}
+
+	trips, err := trip.Load(*tripFile)
+	if err != nil {
+		return errors.Wrap(err, "loading trips")
+	}
+	if len(trips) > 0 {
+		setTrip(trips[len(trips)-1])
+	}
+	// The detector outlives processCan so reconnecting doesn't split a trip.
+	p := &pipeline{
+		segments: segments,
+		detector: trip.NewDetector(),
+		hub:      newStreamHub(*wsOrigins),
+	}
+
+	// Stop on SIGINT and SIGTERM so the current trip is saved.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		log.Printf("shutting down")
+		cancel()
+	}()
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	for _, b := range buses {
+		b := b
+		eg.Go(func() error {
+			for {
+				if err := processCan(ctx, p, b); err != nil {
+					log.Printf("failed to process can on bus %q: %+v", b.name, err)
+				}
+
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.NewTimer(10 * time.Second).C:
+				}
 			}
-		}
-	})
+		})
+	}
 
 	eg.Go(func() error {
 		return errors.Wrap(sysmetrics.Monitor(ctx, *metricPollTime), "sysmetrics")
 	})
 
+	eg.Go(func() error {
+		return monitorBusLoad(ctx, buses, *bitrate, *metricPollTime)
+	})
+
 	mux := http.NewServeMux()
 
 	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/logs", segments)
+	mux.Handle("/logs/", segments)
+	mux.HandleFunc("/stream", p.hub.ServeSSE)
+	mux.HandleFunc("/ws", p.hub.ServeWebSocket)
 
 	s := http.Server{
 		Addr:    *bind,
@@ -82,7 +245,10 @@ func run() error {
 
 	eg.Go(func() error {
 		fmt.Println("Listening...", s.Addr)
-		return errors.Wrap(s.ListenAndServe(), "ListenAndServe")
+		if err := s.ListenAndServe(); err != http.ErrServerClosed {
+			return errors.Wrap(err, "ListenAndServe")
+		}
+		return nil
 	})
 
 	eg.Go(func() error {
@@ -99,87 +265,138 @@ func run() error {
 		return nil
 	})
 
-	return eg.Wait()
-}
-
-func processCan(ctx context.Context) error {
-	log.Printf("streaming from %q", *canAddr)
-	req, err := http.NewRequestWithContext(ctx, "GET", *canAddr, nil)
-	if err != nil {
-		return err
+	err = eg.Wait()
+	if ferr := p.flush(); ferr != nil {
+		log.Printf("%+v", ferr)
 	}
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+	if errors.Cause(err) == context.Canceled {
+		return nil
 	}
-	defer resp.Body.Close()
+	return err
+}
 
-	reader := csv.NewReader(resp.Body)
+// pipeline processes the records from every bus. Records are processed one at
+// a time since the segment log, trip detector and metrics aren't safe for
+// concurrent use. Only the drive bus opens and closes segments and feeds the
+// trip detector so signals from other buses can't interleave with it.
+type pipeline struct {
+	mu       sync.Mutex
+	segments *segmentLog
+	detector *trip.Detector
+	hub      *streamHub
+	// connected is the number of buses currently streaming.
+	connected int
+}
 
-	log.Printf("logging to %s", *logFile)
-	f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0700)
+func processCan(ctx context.Context, p *pipeline, b *bus) error {
+	log.Printf("streaming bus %q from %q", b.name, b.uri)
+	source, err := can.OpenSource(ctx, b.uri)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	logging := false
-	var logged units.MetricBytes
-
-	write := func(buf []byte) error {
-		n, err := f.Write(buf)
-		if err != nil {
-			return err
-		}
-		logged += units.MetricBytes(n)
-		return nil
-	}
+	defer source.Close()
+	p.connect()
+	defer p.disconnect()
 
 	for {
-		row, err := reader.Read()
+		record, err := source.Read()
 		if err != nil {
 			return err
 		}
-
-		frame, err := can.ParseCSV(row)
-		if err != nil {
+		record.Bus = b.name
+		if err := p.process(b, record); err != nil {
 			return err
 		}
+	}
+}
 
-		for key, value := range can.FrameToKV(frame) {
-			set(key, value)
+func (p *pipeline) connect() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.connected++
+}
 
-			if key == can.GearKey {
-				// Log if it's in drive or reverse.
-				logging = value == can.GearDrive || value == can.GearReverse
-			}
-		}
+// disconnect finishes the current drive segment once the last bus has
+// disconnected since the segment is shared by every bus.
+func (p *pipeline) disconnect() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.connected--
+	if p.connected > 0 {
+		return
+	}
+	if err := p.segments.Close(); err != nil {
+		log.Printf("failed to close segment: %+v", err)
+	}
+}
 
-		if logging {
-			record := can.Record{
-				Frame: frame,
-				Time:  time.Now(),
-			}
-			body, err := json.Marshal(record)
-			if err != nil {
-				return err
-			}
-			if err := write(body); err != nil {
-				return err
-			}
-			if err := write([]byte("\n")); err != nil {
-				return err
-			}
+func (p *pipeline) process(b *bus, record can.Record) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	recordStats(b, record)
+
+	values := b.db.DecodeValues(record.Frame)
+	p.hub.Publish(b.name, record.Time, values)
+	for _, v := range values {
+		if group, index, ok := v.Signal.MuxGroup(); ok {
+			setIndexed(b.name, group, v.Signal.Help(), index, v.Value)
+		} else {
+			setSignal(b.name, v.Signal.Name, v.Signal.Help(), v.Value)
+		}
+		if len(v.Signal.Values) > 0 {
+			setState(b.name, v.Signal, v.Value)
 		}
 
-		// If we've logged more than 1GB truncate the file.
-		if logged >= 1*units.GB {
-			if _, err := f.Seek(0, 0); err != nil {
-				return err
-			}
-			if err := f.Truncate(0); err != nil {
-				return err
+		if b.drive && v.Signal.Name == can.GearKey {
+			// Log each drive to its own segment, starting when it's put in
+			// drive or reverse and ending when it's parked.
+			switch v.Value {
+			case can.GearDrive, can.GearReverse:
+				if err := p.segments.Open(record.Time); err != nil {
+					return err
+				}
+			case can.GearPark:
+				if err := p.segments.Close(); err != nil {
+					return err
+				}
 			}
-			logged = 0
 		}
 	}
+
+	if err := p.segments.Write(record); err != nil {
+		return err
+	}
+
+	if !b.drive {
+		return nil
+	}
+	if t, ok := p.detector.Update(record.Time, values); ok {
+		return saveTrip(t)
+	}
+	return nil
+}
+
+// flush ends and saves the current trip when shutting down.
+func (p *pipeline) flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if t, ok := p.detector.Flush(); ok {
+		return saveTrip(t)
+	}
+	return nil
+}
+
+func saveTrip(t trip.Trip) error {
+	log.Printf("trip ended: %.1f mi, %.2f kWh", t.DistanceMiles, t.EnergyUsedKWh)
+	setTrip(t)
+	return errors.Wrap(trip.Append(*tripFile, t), "saving trip")
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(buf []byte) (int, error) {
+	return f(buf)
 }
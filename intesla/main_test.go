@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+	"github.com/d4l3k/ricela/trip"
+)
+
+func TestPipelineFlush(t *testing.T) {
+	l, cleanup := tempSegmentLog(t, 0, 0)
+	defer cleanup()
+	oldTripFile := *tripFile
+	*tripFile = filepath.Join(l.dir, "trips.jsonl")
+	defer func() { *tripFile = oldTripFile }()
+
+	db, err := can.ParseDBC(strings.NewReader(gearDBC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &bus{name: "vehicle", db: db, stats: can.NewStats(), drive: true}
+	p := &pipeline{segments: l, detector: trip.NewDetector(), hub: newStreamHub("")}
+
+	if err := p.flush(); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2021, 2, 1, 12, 0, 0, 0, time.Local)
+	for i := 0; i < 3; i++ {
+		record := can.Record{Frame: can.Frame{ID: 1, Data: []byte{can.GearDrive, 30}}, Time: start.Add(time.Duration(i) * time.Second)}
+		if err := p.process(b, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if trips, err := trip.Load(*tripFile); err != nil || len(trips) != 0 {
+		t.Fatalf("trips before shutting down = %+v, %v", trips, err)
+	}
+
+	// Shutting down mid drive saves the trip so far.
+	if err := p.flush(); err != nil {
+		t.Fatal(err)
+	}
+	trips, err := trip.Load(*tripFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trips) != 1 || !trips[0].Start.Equal(start) || trips[0].Duration() != 2*time.Second {
+		t.Errorf("trips = %+v; expected one 2s trip", trips)
+	}
+	if err := p.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if trips, err := trip.Load(*tripFile); err != nil || len(trips) != 1 {
+		t.Errorf("trips after flushing again = %+v, %v", trips, err)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/d4l3k/ricela/can"
+	"github.com/pkg/errors"
+)
+
+const (
+	segmentPrefix     = "drive-"
+	segmentTimeLayout = "20060102-150405"
+
+	// maxSegmentSize is the size at which a drive is split into a new segment.
+	maxSegmentSize = 1 * units.GB
+	// segmentPruneSize is how much is written to a segment between applying
+	// the retention policy, so long drives don't exceed the size limit.
+	segmentPruneSize = 16 * units.MB
+)
+
+// segmentExtensions are the file extensions used for each log format.
+var segmentExtensions = map[can.Format]string{
+	can.FormatJSON:    ".json",
+	can.FormatCandump: ".log",
+	can.FormatASC:     ".asc",
+	can.FormatGVRET:   ".csv",
+	can.FormatBinary:  ".bin",
+}
+
+// segmentLog records each drive into its own timestamped file in dir and
+// deletes old segments to stay within the retention limits.
+type segmentLog struct {
+	dir     string
+	format  can.Format
+	maxSize units.MetricBytes
+	maxAge  time.Duration
+
+	// segmentSize is the size at which segments are split and pruneSize how
+	// much is written between pruning.
+	segmentSize units.MetricBytes
+	pruneSize   units.MetricBytes
+
+	f      *os.File
+	writer can.RecordWriter
+	size   units.MetricBytes
+	// pruned is the size of the open segment when it was last pruned.
+	pruned units.MetricBytes
+}
+
+// Segment describes a log file on disk.
+type Segment struct {
+	Name    string            `json:"name"`
+	Size    units.MetricBytes `json:"size"`
+	ModTime time.Time         `json:"mod_time"`
+}
+
+// logFileSegments returns the log directory and format replacing the
+// deprecated -logfile flag. Segments are written next to the old file in the
+// format matching its extension, JSON if it isn't known.
+func logFileSegments(path string) (string, can.Format) {
+	format := can.FormatJSON
+	for f, ext := range segmentExtensions {
+		if strings.EqualFold(filepath.Ext(path), ext) {
+			format = f
+		}
+	}
+	return filepath.Dir(path), format
+}
+
+func newSegmentLog(dir string, format can.Format, maxSize units.MetricBytes, maxAge time.Duration) (*segmentLog, error) {
+	if _, ok := segmentExtensions[format]; !ok {
+		return nil, errors.Errorf("unknown log format %q", format)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	// Split segments small enough that old ones can be deleted to make room.
+	segmentSize := units.MetricBytes(maxSegmentSize)
+	if maxSize > 0 && maxSize/2 < segmentSize {
+		segmentSize = maxSize / 2
+	}
+	return &segmentLog{
+		dir:         dir,
+		format:      format,
+		maxSize:     maxSize,
+		maxAge:      maxAge,
+		segmentSize: segmentSize,
+		pruneSize:   segmentPruneSize,
+	}, nil
+}
+
+// Open starts a new segment if one isn't already open.
+func (l *segmentLog) Open(t time.Time) error {
+	if l.f != nil {
+		return nil
+	}
+	name := segmentPrefix + t.Format(segmentTimeLayout) + segmentExtensions[l.format]
+	path := filepath.Join(l.dir, name)
+	log.Printf("logging to %s", path)
+	// Append in case we reconnect within the same second.
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	counted := writerFunc(func(buf []byte) (int, error) {
+		n, err := f.Write(buf)
+		l.size += units.MetricBytes(n)
+		return n, err
+	})
+	writer, err := can.NewRecordWriter(l.format, counted)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f = f
+	l.writer = writer
+	l.size = 0
+	l.pruned = 0
+	return nil
+}
+
+// Write logs a record to the open segment, if any. Large segments are split
+// and the retention policy is applied as the segment grows.
+func (l *segmentLog) Write(record can.Record) error {
+	if l.f == nil {
+		return nil
+	}
+	if err := l.writer.Write(record); err != nil {
+		return err
+	}
+	if l.size >= l.segmentSize {
+		if err := l.Close(); err != nil {
+			return err
+		}
+		return l.Open(record.Time)
+	}
+	if l.size-l.pruned >= l.pruneSize {
+		l.pruned = l.size
+		return l.Prune(time.Now())
+	}
+	return nil
+}
+
+// Close finishes the open segment and applies the retention policy.
+func (l *segmentLog) Close() error {
+	if l.f == nil {
+		return nil
+	}
+	err := l.writer.Close()
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	l.writer = nil
+	if err != nil {
+		return err
+	}
+	return l.Prune(time.Now())
+}
+
+// Segments lists the segments in the log directory, oldest first.
+func (l *segmentLog) Segments() ([]Segment, error) {
+	files, err := ioutil.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []Segment
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), segmentPrefix) {
+			continue
+		}
+		segments = append(segments, Segment{
+			Name:    file.Name(),
+			Size:    units.MetricBytes(file.Size()),
+			ModTime: file.ModTime(),
+		})
+	}
+	// The names start with the time so they sort chronologically.
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].Name < segments[j].Name
+	})
+	return segments, nil
+}
+
+// Prune deletes segments older than maxAge and then the oldest segments until
+// the total size is under maxSize. The open segment is never deleted.
+func (l *segmentLog) Prune(now time.Time) error {
+	segments, err := l.Segments()
+	if err != nil {
+		return err
+	}
+	var open string
+	if l.f != nil {
+		open = filepath.Base(l.f.Name())
+	}
+
+	var total units.MetricBytes
+	for _, s := range segments {
+		total += s.Size
+	}
+	for _, s := range segments {
+		if s.Name == open {
+			continue
+		}
+		expired := l.maxAge > 0 && now.Sub(s.ModTime) > l.maxAge
+		full := l.maxSize > 0 && total > l.maxSize
+		if !expired && !full {
+			continue
+		}
+		log.Printf("deleting log segment %s", s.Name)
+		if err := os.Remove(filepath.Join(l.dir, s.Name)); err != nil {
+			return err
+		}
+		total -= s.Size
+	}
+	return nil
+}
+
+// ServeHTTP lists the segments as JSON on /logs and serves the segment files
+// on /logs/<name>.
+func (l *segmentLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/logs"), "/")
+	if name == "" {
+		segments, err := l.Segments()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(segments); err != nil {
+			log.Printf("failed to write segments: %+v", err)
+		}
+		return
+	}
+
+	if name != filepath.Base(name) || !strings.HasPrefix(name, segmentPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Disposition", "attachment; filename="+name)
+	http.ServeFile(w, r, filepath.Join(l.dir, name))
+}
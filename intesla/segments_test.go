@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/d4l3k/ricela/can"
+	"github.com/d4l3k/ricela/trip"
+)
+
+func TestLogFileSegments(t *testing.T) {
+	cases := []struct {
+		path   string
+		dir    string
+		format can.Format
+	}{
+		{"log.json", ".", can.FormatJSON},
+		{filepath.Join("data", "car.bin"), "data", can.FormatBinary},
+		{filepath.Join("data", "car.LOG"), "data", can.FormatCandump},
+		{filepath.Join("data", "car.txt"), "data", can.FormatJSON},
+	}
+	for _, c := range cases {
+		dir, format := logFileSegments(c.path)
+		if dir != c.dir || format != c.format {
+			t.Errorf("logFileSegments(%q) = %q, %q; expected %q, %q", c.path, dir, format, c.dir, c.format)
+		}
+	}
+}
+
+func tempSegmentLog(t *testing.T, maxSize units.MetricBytes, maxAge time.Duration) (*segmentLog, func()) {
+	dir, err := ioutil.TempDir("", "segments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := newSegmentLog(dir, can.FormatCandump, maxSize, maxAge)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return l, func() { os.RemoveAll(dir) }
+}
+
+// writeSegment writes a segment file of the given size and age.
+func writeSegment(t *testing.T, l *segmentLog, name string, size int, modTime time.Time) {
+	path := filepath.Join(l.dir, name)
+	if err := ioutil.WriteFile(path, make([]byte, size), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func segmentNames(t *testing.T, l *segmentLog) []string {
+	segments, err := l.Segments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range segments {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestSegmentPrune(t *testing.T) {
+	l, cleanup := tempSegmentLog(t, 250, 24*time.Hour)
+	defer cleanup()
+
+	now := time.Now()
+	writeSegment(t, l, "drive-20210101-000000.log", 100, now.Add(-48*time.Hour))
+	writeSegment(t, l, "drive-20210102-000000.log", 100, now.Add(-3*time.Hour))
+	writeSegment(t, l, "drive-20210103-000000.log", 100, now.Add(-2*time.Hour))
+	writeSegment(t, l, "drive-20210104-000000.log", 100, now.Add(-time.Hour))
+	writeSegment(t, l, "other.log", 1000, now.Add(-48*time.Hour))
+
+	// The first is too old and the second is deleted to fit in 250 bytes.
+	if err := l.Prune(now); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"drive-20210103-000000.log", "drive-20210104-000000.log"}
+	if got := segmentNames(t, l); !reflect.DeepEqual(got, want) {
+		t.Errorf("segments after pruning = %v; expected %v", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(l.dir, "other.log")); err != nil {
+		t.Errorf("pruning deleted a file that isn't a segment: %v", err)
+	}
+}
+
+func TestSegmentPruneWhileWriting(t *testing.T) {
+	l, cleanup := tempSegmentLog(t, 30000, 0)
+	defer cleanup()
+	l.pruneSize = 4096
+	// Don't split the drive so only pruning keeps it under the limit.
+	l.segmentSize = maxSegmentSize
+
+	now := time.Now()
+	writeSegment(t, l, "drive-20210101-000000.log", 10000, now)
+	writeSegment(t, l, "drive-20210102-000000.log", 9000, now)
+
+	start := time.Date(2021, 2, 1, 12, 0, 0, 0, time.Local)
+	if err := l.Open(start); err != nil {
+		t.Fatal(err)
+	}
+	// The writer is buffered so the segment grows 4KB at a time.
+	for i := 0; l.size < 15000; i++ {
+		if i > 10000 {
+			t.Fatalf("segment size = %s after %d records", l.size, i)
+		}
+		record := can.Record{Frame: can.Frame{ID: 0x118, Data: make([]byte, 8)}, Time: start.Add(time.Duration(i) * time.Millisecond)}
+		if err := l.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The oldest segment is deleted before the drive ends.
+	want := []string{"drive-20210102-000000.log", "drive-20210201-120000.log"}
+	if got := segmentNames(t, l); !reflect.DeepEqual(got, want) {
+		t.Errorf("segments while writing = %v; expected %v", got, want)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSegmentSplit(t *testing.T) {
+	l, cleanup := tempSegmentLog(t, 0, 0)
+	defer cleanup()
+	if l.segmentSize != maxSegmentSize {
+		t.Errorf("segmentSize = %s; expected %s", l.segmentSize, units.MetricBytes(maxSegmentSize))
+	}
+	if small, cleanup := tempSegmentLog(t, 100*units.MB, 0); small.segmentSize != 50*units.MB {
+		t.Errorf("segmentSize with a 100MB limit = %s; expected 50MB", small.segmentSize)
+		cleanup()
+	} else {
+		cleanup()
+	}
+
+	l.segmentSize = 4096
+	start := time.Date(2021, 2, 1, 12, 0, 0, 0, time.Local)
+	if err := l.Open(start); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 500; i++ {
+		record := can.Record{Frame: can.Frame{ID: 0x118, Data: make([]byte, 8)}, Time: start.Add(time.Duration(i) * time.Second)}
+		if err := l.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := segmentNames(t, l); len(got) < 2 {
+		t.Errorf("segments = %v; expected the drive to be split", got)
+	}
+}
+
+const gearDBC = `VERSION ""
+
+BO_ 1 drive: 2 Vector__XXX
+ SG_ gear : 0|8@1+ (1,0) [0|7] "" Vector__XXX
+ SG_ signed_speed : 8|8@1+ (1,0) [0|255] "mph" Vector__XXX
+`
+
+func TestPipelineGearSegments(t *testing.T) {
+	l, cleanup := tempSegmentLog(t, 0, 0)
+	defer cleanup()
+	oldTripFile := *tripFile
+	*tripFile = filepath.Join(l.dir, "trips.jsonl")
+	defer func() { *tripFile = oldTripFile }()
+
+	db, err := can.ParseDBC(strings.NewReader(gearDBC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &bus{name: "vehicle", db: db, stats: can.NewStats(), drive: true}
+	p := &pipeline{segments: l, detector: trip.NewDetector(), hub: newStreamHub("")}
+
+	start := time.Date(2021, 2, 1, 12, 0, 0, 0, time.Local)
+	gears := []struct {
+		offset time.Duration
+		gear   byte
+		open   bool
+	}{
+		{0, can.GearPark, false},
+		{time.Second, can.GearDrive, true},
+		{2 * time.Second, can.GearDrive, true},
+		{3 * time.Second, can.GearPark, false},
+		{time.Minute, can.GearReverse, true},
+		{time.Minute + time.Second, can.GearPark, false},
+	}
+	for _, g := range gears {
+		record := can.Record{Frame: can.Frame{ID: 1, Data: []byte{g.gear}}, Time: start.Add(g.offset)}
+		if err := p.process(b, record); err != nil {
+			t.Fatal(err)
+		}
+		if open := l.f != nil; open != g.open {
+			t.Errorf("gear %d at %s: segment open = %t; expected %t", g.gear, g.offset, open, g.open)
+		}
+	}
+
+	want := []string{"drive-20210201-120001.log", "drive-20210201-120100.log"}
+	if got := segmentNames(t, l); !reflect.DeepEqual(got, want) {
+		t.Fatalf("segments = %v; expected %v", got, want)
+	}
+	// The first drive has both frames in drive.
+	body, err := ioutil.ReadFile(filepath.Join(l.dir, want[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(body), "\n"); n != 2 {
+		t.Errorf("first segment has %d frames; expected 2:\n%s", n, body)
+	}
+}
+
+func TestSegmentHTTP(t *testing.T) {
+	l, cleanup := tempSegmentLog(t, 0, 0)
+	defer cleanup()
+	writeSegment(t, l, "drive-20210101-000000.log", 10, time.Now())
+	// A file outside the log directory.
+	secret := filepath.Join(filepath.Dir(l.dir), filepath.Base(l.dir)+"-secret")
+	if err := ioutil.WriteFile(secret, []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(secret)
+
+	mux := http.NewServeMux()
+	mux.Handle("/logs", l)
+	mux.Handle("/logs/", l)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var segments []Segment
+	err = json.NewDecoder(resp.Body).Decode(&segments)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 1 || segments[0].Name != "drive-20210101-000000.log" || segments[0].Size != 10 {
+		t.Errorf("GET /logs = %+v", segments)
+	}
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+	if code, body := get("/logs/drive-20210101-000000.log"); code != http.StatusOK || len(body) != 10 {
+		t.Errorf("download = %d with %d bytes", code, len(body))
+	}
+	for _, path := range []string{
+		"/logs/drive-missing.log",
+		"/logs/other.log",
+		"/logs/..%2F" + filepath.Base(secret),
+		"/logs/drive-x%2F..%2F..%2F" + filepath.Base(secret),
+	} {
+		if code, body := get(path); code != http.StatusNotFound || strings.Contains(body, "secret") {
+			t.Errorf("GET %s = %d %q; expected not found", path, code, body)
+		}
+	}
+
+	// ServeMux cleans paths before they get here so call the handler
+	// directly to check it rejects names outside the directory.
+	for _, name := range []string{
+		"../" + filepath.Base(secret),
+		"drive-../../" + filepath.Base(secret),
+		"drive-x/../../" + filepath.Base(secret),
+	} {
+		req := httptest.NewRequest("GET", "/logs/", nil)
+		req.URL.Path = "/logs/" + name
+		w := httptest.NewRecorder()
+		l.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound || strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("ServeHTTP(%q) = %d %q; expected not found", name, w.Code, w.Body)
+		}
+	}
+}
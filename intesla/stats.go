@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	frameCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "canbus:frames_total",
+		Help: "Number of frames received per ID.",
+	}, []string{"bus", "id", "decoded"})
+	frameRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "canbus:frame_rate_hz",
+		Help: "Smoothed frame rate per ID.",
+	}, []string{"bus", "id"})
+	frameJitter = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "canbus:frame_jitter_seconds",
+		Help: "Smoothed deviation of the time between frames per ID.",
+	}, []string{"bus", "id"})
+	frameDLC = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "canbus:frame_dlc",
+		Help: "Data length of the latest frame per ID.",
+	}, []string{"bus", "id"})
+	frameChangingBits = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "canbus:frame_changing_bits",
+		Help: "Number of data bits that have changed per ID.",
+	}, []string{"bus", "id"})
+	busLoad = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "canbus:bus_load_ratio",
+		Help: "Estimated fraction of the bus bandwidth in use.",
+	}, []string{"bus"})
+)
+
+// idLabel formats the ID of the statistics, extended IDs are always 8 digits
+// so they don't collide with standard ones.
+func idLabel(st can.IDStats) string {
+	if st.Extended {
+		return fmt.Sprintf("0x%08X", st.ID)
+	}
+	return fmt.Sprintf("0x%03X", st.ID)
+}
+
+// recordStats updates the frame statistics of the bus and their metrics.
+func recordStats(b *bus, record can.Record) {
+	st := b.stats.Add(record)
+	id := idLabel(st)
+	_, decoded := b.db.Messages[st.Key()]
+	frameCount.WithLabelValues(b.name, id, strconv.FormatBool(decoded)).Inc()
+	frameRate.WithLabelValues(b.name, id).Set(st.Rate())
+	frameJitter.WithLabelValues(b.name, id).Set(st.Jitter.Seconds())
+	frameDLC.WithLabelValues(b.name, id).Set(float64(st.DLC))
+	frameChangingBits.WithLabelValues(b.name, id).Set(float64(st.ChangingBits()))
+}
+
+// monitorBusLoad periodically exports the estimated load of each bus and the
+// frame rates, so IDs that stop sending drop to 0.
+func monitorBusLoad(ctx context.Context, buses []*bus, bitrate float64, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		now := time.Now()
+		for _, b := range buses {
+			busLoad.WithLabelValues(b.name).Set(b.stats.BusLoad(bitrate, now))
+			for _, st := range b.stats.IDs() {
+				frameRate.WithLabelValues(b.name, idLabel(st)).Set(st.RateAt(now))
+			}
+		}
+	}
+}
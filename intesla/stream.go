@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// sseKeepAlive is how often a comment is sent on idle event streams so
+// proxies don't time them out.
+const sseKeepAlive = 15 * time.Second
+
+var streamClients = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "canbus:stream_clients",
+	Help: "Number of connected live stream clients.",
+}, []string{"transport"})
+
+// streamUpdate is a set of decoded values sent to stream clients.
+type streamUpdate struct {
+	Time   time.Time          `json:"time"`
+	Bus    string             `json:"bus,omitempty"`
+	Values map[string]float64 `json:"values"`
+}
+
+type streamKey struct {
+	bus  string
+	name string
+}
+
+type streamValue struct {
+	time  time.Time
+	value float64
+}
+
+// subscriber buffers the latest value of each key for a stream client until
+// it's due to be sent.
+type subscriber struct {
+	filter   *regexp.Regexp
+	interval time.Duration
+	notify   chan struct{}
+
+	mu      sync.Mutex
+	pending map[streamKey]streamValue
+	sent    map[streamKey]time.Time
+}
+
+// add queues a value, replacing any pending value for the same key.
+func (s *subscriber) add(key streamKey, v streamValue) {
+	if s.filter != nil && !s.filter.MatchString(key.name) {
+		return
+	}
+	s.mu.Lock()
+	s.pending[key] = v
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// due removes the pending values that are due to be sent at now and returns
+// them grouped by bus. It also returns how long until the next remaining
+// value is due, or 0 if there are none.
+func (s *subscriber) due(now time.Time) ([]streamUpdate, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updates := map[string]*streamUpdate{}
+	var wait time.Duration
+	for key, v := range s.pending {
+		if next := s.sent[key].Add(s.interval); next.After(now) {
+			if d := next.Sub(now); wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+		delete(s.pending, key)
+		s.sent[key] = now
+		u, ok := updates[key.bus]
+		if !ok {
+			u = &streamUpdate{Bus: key.bus, Values: map[string]float64{}}
+			updates[key.bus] = u
+		}
+		u.Values[key.name] = v.value
+		if v.time.After(u.Time) {
+			u.Time = v.time
+		}
+	}
+
+	var out []streamUpdate
+	for _, u := range updates {
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Bus < out[j].Bus
+	})
+	return out, wait
+}
+
+// streamHub fans decoded values out to the connected stream clients.
+type streamHub struct {
+	// origins are the browser origins other than the server's own that may
+	// open WebSocket streams.
+	origins map[string]bool
+
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+// newStreamHub returns a hub allowing the comma separated origins.
+func newStreamHub(origins string) *streamHub {
+	h := &streamHub{
+		origins:     map[string]bool{},
+		subscribers: map[*subscriber]struct{}{},
+	}
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			h.origins[origin] = true
+		}
+	}
+	return h
+}
+
+// checkOrigin reports whether a WebSocket request may be accepted. Browsers
+// don't apply the same-origin policy to WebSockets so requests from other
+// pages have to be rejected here. Requests without an Origin aren't from
+// browsers and are allowed.
+func (h *streamHub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if h.origins[origin] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// Publish sends the values decoded from a frame to every subscriber. It never
+// blocks on slow clients, they only get the latest value of each key.
+func (h *streamHub) Publish(bus string, t time.Time, values []can.Value) {
+	if len(values) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subscribers {
+		for _, v := range values {
+			s.add(streamKey{bus: bus, name: v.Signal.Name}, streamValue{time: t, value: v.Value})
+		}
+	}
+}
+
+// subscribe registers a client using the filter and interval query
+// parameters.
+func (h *streamHub) subscribe(r *http.Request) (*subscriber, error) {
+	s := &subscriber{
+		notify:  make(chan struct{}, 1),
+		pending: map[streamKey]streamValue{},
+		sent:    map[streamKey]time.Time{},
+	}
+	if filter := r.FormValue("filter"); filter != "" {
+		re, err := regexp.Compile(filter)
+		if err != nil {
+			return nil, errors.Wrap(err, "filter")
+		}
+		s.filter = re
+	}
+	if interval := r.FormValue("interval"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, errors.Wrap(err, "interval")
+		}
+		if d < 0 {
+			return nil, errors.Errorf("interval %s is negative", d)
+		}
+		s.interval = d
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[s] = struct{}{}
+	return s, nil
+}
+
+func (h *streamHub) unsubscribe(s *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, s)
+}
+
+// run calls send with the updates for s as they become due until ctx is done
+// or send fails.
+func (h *streamHub) run(ctx context.Context, s *subscriber, send func(streamUpdate) error, keepAlive func() error) error {
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := keepAlive(); err != nil {
+				return err
+			}
+			continue
+		case <-s.notify:
+		case <-timer.C:
+		}
+
+		updates, wait := s.due(time.Now())
+		for _, u := range updates {
+			if err := send(u); err != nil {
+				return err
+			}
+		}
+		if wait > 0 {
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(wait)
+		}
+	}
+}
+
+// ServeSSE streams updates as Server-Sent Events. The filter query parameter
+// is a regexp the signal names must match and interval limits how often each
+// signal is sent, such as ?filter=speed|power&interval=250ms.
+func (h *streamHub) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	s, err := h.subscribe(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer h.unsubscribe(s)
+	streamClients.WithLabelValues("sse").Inc()
+	defer streamClients.WithLabelValues("sse").Dec()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(u streamUpdate) error {
+		body, err := json.Marshal(u)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", body); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	keepAlive := func() error {
+		if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	if err := h.run(r.Context(), s, send, keepAlive); err != nil && err != context.Canceled {
+		log.Printf("sse stream: %v", err)
+	}
+}
+
+// ServeWebSocket streams updates as WebSocket text messages, taking the same
+// query parameters as ServeSSE.
+func (h *streamHub) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !h.checkOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	s, err := h.subscribe(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer h.unsubscribe(s)
+
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		log.Printf("websocket: %v", err)
+		return
+	}
+	defer conn.Close()
+	streamClients.WithLabelValues("websocket").Inc()
+	defer streamClients.WithLabelValues("websocket").Dec()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	// Read until the client closes the connection, answering pings.
+	go func() {
+		defer cancel()
+		if err := conn.readLoop(); err != nil {
+			log.Printf("websocket: %v", err)
+		}
+	}()
+
+	send := func(u streamUpdate) error {
+		body, err := json.Marshal(u)
+		if err != nil {
+			return err
+		}
+		return conn.WriteMessage(wsOpText, body)
+	}
+	keepAlive := func() error {
+		return conn.WriteMessage(wsOpPing, nil)
+	}
+	if err := h.run(ctx, s, send, keepAlive); err != nil && err != context.Canceled {
+		log.Printf("websocket: %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+)
+
+// publishWhenSubscribed publishes a value once a client has subscribed to h.
+func publishWhenSubscribed(t *testing.T, h *streamHub, value float64) {
+	signal := can.DefaultDBC.Signal("battery_voltage")
+	if signal == nil {
+		t.Fatal("missing battery_voltage signal")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		h.mu.Lock()
+		n := len(h.subscribers)
+		h.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a subscriber")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	h.Publish("vehicle", time.Unix(1600000000, 0), []can.Value{
+		{Signal: signal, Value: value},
+		{Signal: &can.Signal{Name: "filtered_out"}, Value: 1},
+	})
+}
+
+func checkUpdate(t *testing.T, body []byte, value float64) {
+	var u streamUpdate
+	if err := json.Unmarshal(body, &u); err != nil {
+		t.Fatalf("invalid update %q: %v", body, err)
+	}
+	if u.Bus != "vehicle" || len(u.Values) != 1 || u.Values["battery_voltage"] != value {
+		t.Errorf("update = %+v; expected battery_voltage = %g on vehicle", u, value)
+	}
+}
+
+func TestStreamSSE(t *testing.T) {
+	h := newStreamHub("")
+	server := httptest.NewServer(http.HandlerFunc(h.ServeSSE))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?filter=^battery&interval=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q; expected text/event-stream", ct)
+	}
+
+	publishWhenSubscribed(t, h, 400.5)
+	r := bufio.NewReader(resp.Body)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(line, "data: ") {
+		t.Fatalf("got line %q; expected an event", line)
+	}
+	checkUpdate(t, []byte(strings.TrimPrefix(strings.TrimSpace(line), "data: ")), 400.5)
+	if line, err := r.ReadString('\n'); err != nil || line != "\n" {
+		t.Errorf("got %q, %v after the event; expected a blank line", line, err)
+	}
+}
+
+func TestStreamSubscribeErrors(t *testing.T) {
+	h := newStreamHub("")
+	server := httptest.NewServer(http.HandlerFunc(h.ServeSSE))
+	defer server.Close()
+
+	for _, query := range []string{"filter=(", "interval=soon", "interval=-1s"} {
+		resp, err := http.Get(server.URL + "?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("?%s: status %d; expected %d", query, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
+// dialWebSocket performs the opening handshake and returns the connection and
+// the response.
+func dialWebSocket(t *testing.T, server *httptest.Server, path string, header http.Header) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("GET", server.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, r, resp
+}
+
+// writeClientFrame writes a masked client frame.
+func writeClientFrame(t *testing.T, conn net.Conn, op byte, payload []byte) {
+	mask := [4]byte{0x12, 0x34, 0x56, 0x78}
+	frame := []byte{0x80 | op, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readServerFrame reads an unmasked server frame.
+func readServerFrame(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	if header[0]&0x80 == 0 {
+		t.Fatalf("fragmented server frame %x", header)
+	}
+	if header[1]&0x80 != 0 {
+		t.Fatalf("masked server frame %x", header)
+	}
+	n := int(header[1] & 0x7F)
+	if n == 126 {
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			t.Fatal(err)
+		}
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatal(err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+func TestStreamWebSocket(t *testing.T) {
+	h := newStreamHub("")
+	server := httptest.NewServer(http.HandlerFunc(h.ServeWebSocket))
+	defer server.Close()
+
+	conn, r, resp := dialWebSocket(t, server, "/?filter=^battery", nil)
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status %d; expected %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+	// The example key and accept value from RFC 6455.
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", accept)
+	}
+
+	publishWhenSubscribed(t, h, 380)
+	op, payload := readServerFrame(t, r)
+	if op != wsOpText {
+		t.Fatalf("opcode %#x; expected text", op)
+	}
+	checkUpdate(t, payload, 380)
+
+	writeClientFrame(t, conn, wsOpPing, []byte("hello"))
+	if op, payload := readServerFrame(t, r); op != wsOpPong || string(payload) != "hello" {
+		t.Errorf("got %#x %q; expected pong with the ping payload", op, payload)
+	}
+
+	// The close status is echoed and the connection is closed.
+	writeClientFrame(t, conn, wsOpClose, []byte{0x03, 0xE8, 'b', 'y', 'e'})
+	if op, payload := readServerFrame(t, r); op != wsOpClose || string(payload) != "\x03\xe8" {
+		t.Errorf("got %#x %q; expected close with status 1000", op, payload)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Errorf("read after close = %v; expected EOF", err)
+	}
+}
+
+func TestStreamWebSocketUnmasked(t *testing.T) {
+	h := newStreamHub("")
+	server := httptest.NewServer(http.HandlerFunc(h.ServeWebSocket))
+	defer server.Close()
+
+	conn, r, resp := dialWebSocket(t, server, "/", nil)
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status %d; expected %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+	if _, err := conn.Write([]byte{0x80 | wsOpText, 2, 'h', 'i'}); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Errorf("read after unmasked frame = %v; expected the connection to be closed", err)
+	}
+}
+
+func TestStreamWebSocketOrigin(t *testing.T) {
+	h := newStreamHub("https://dash.example.com/, http://localhost:3000")
+	server := httptest.NewServer(http.HandlerFunc(h.ServeWebSocket))
+	defer server.Close()
+
+	host := server.Listener.Addr().String()
+	cases := []struct {
+		origin string
+		want   int
+	}{
+		{"", http.StatusSwitchingProtocols},
+		{"http://" + host, http.StatusSwitchingProtocols},
+		{"https://dash.example.com", http.StatusSwitchingProtocols},
+		{"http://localhost:3000", http.StatusSwitchingProtocols},
+		{"https://evil.example.com", http.StatusForbidden},
+		{"http://localhost:3001", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		header := http.Header{}
+		if c.origin != "" {
+			header.Set("Origin", c.origin)
+		}
+		conn, _, resp := dialWebSocket(t, server, "/", header)
+		conn.Close()
+		if resp.StatusCode != c.want {
+			t.Errorf("Origin %q: status %d; expected %d", c.origin, resp.StatusCode, c.want)
+		}
+	}
+}
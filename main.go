@@ -15,6 +15,8 @@ import (
 
 	"github.com/cenkalti/backoff"
 	"github.com/d4l3k/ricela/chargepoint"
+	"github.com/d4l3k/ricela/notify"
+	"github.com/d4l3k/ricela/policy"
 	"github.com/d4l3k/ricela/sysmetrics"
 	"github.com/golang/geo/s2"
 
@@ -33,12 +35,27 @@ var (
 	drivePollTime       = flag.Duration("drivePollTime", 15*time.Second, "polling frequency")
 	activePollTime      = flag.Duration("activePollTime", 5*time.Second, "polling frequency")
 	chargePointPollTime = flag.Duration("chargePointPollTime", 5*time.Minute, "polling frequency")
+	chargingPollTime    = flag.Duration("chargingPollTime", 30*time.Second, "polling frequency while charging so sessions are stopped as soon as they complete")
+	idleGrace           = flag.Duration("idleGrace", 10*time.Minute, "how long the car can stay plugged in after charging stops before idle fees are charged, chargers can override it with idle_grace_minutes")
 	carServerAddr       = flag.String("carserver", "http://localhost:27654/diag_vitals", "car server vitals endpoint")
+	chargersFile        = flag.String("chargers", "chargers.json", "JSON file with the chargers to start charging at")
+	chargersPollTime    = flag.Duration("chargersPollTime", 10*time.Second, "how often to check the chargers file for changes")
+	discoverAllow       = flag.String("discoverAllow", "", "regexp of ChargePoint station names, addresses or device IDs that may be started when there's no configured charger nearby, empty disables discovery")
+	discoverRadius      = flag.Float64("discoverRadius", 50, "distance in meters around the car to search for stations")
+	discoverLevel       = flag.String("discoverLevel", "", "only discover stations with this power level, l2 or dc")
+	discoverFree        = flag.Bool("discoverFree", false, "only discover free stations")
+	policyTargetSOC     = flag.Float64("policyTargetSOC", 0, "stop charging at this battery percentage regardless of the car's charge limit, 0 to disable")
+	policyMaxCost       = flag.Float64("policyMaxCost", 0, "stop charging once a ChargePoint session costs this much, updated every chargePointPollTime, 0 to disable")
+	policyMaxKWh        = flag.Float64("policyMaxKWh", 0, "stop charging after adding this many kWh, 0 to disable")
+	notifyConfig        = flag.String("notify", "", "JSON file configuring notifications, empty disables them")
+	unlockedNotify      = flag.Duration("unlockedNotify", 10*time.Minute, "notify when the car has been parked and unlocked this long")
+	policyHours         = flag.String("policyHours", "", "hours charging is allowed in local time such as 22-7,12-14, empty for any time")
 )
 
 const (
-	StateCharging = "Charging"
-	StateComplete = "Complete"
+	StateCharging     = "Charging"
+	StateComplete     = "Complete"
+	StateDisconnected = "Disconnected"
 )
 
 type Charger interface {
@@ -48,7 +65,9 @@ type Charger interface {
 
 type ChargePointCharger struct {
 	DeviceID int64
-	LatLng   s2.LatLng
+	// Port is the port to start, 0 for any.
+	Port   int
+	LatLng s2.LatLng
 }
 
 func (c ChargePointCharger) DistanceInMeters(a s2.LatLng) float64 {
@@ -57,13 +76,16 @@ func (c ChargePointCharger) DistanceInMeters(a s2.LatLng) float64 {
 	return earthRadius * angle.Radians()
 }
 
-func (c ChargePointCharger) Start(ctx context.Context, r *RiceLa) error {
-	_, err := r.chargepoint.StartSession(ctx, c.DeviceID)
-	return err
+func (c ChargePointCharger) String() string {
+	if c.Port != 0 {
+		return fmt.Sprintf("ChargePoint station %d port %d", c.DeviceID, c.Port)
+	}
+	return fmt.Sprintf("ChargePoint station %d", c.DeviceID)
 }
 
-var knownChargers = []Charger{
-	ChargePointCharger{DeviceID: 1947511, LatLng: s2.LatLngFromDegrees(47.630007, -122.133969)},
+func (c ChargePointCharger) Start(ctx context.Context, r *RiceLa) error {
+	_, err := r.chargepoint.StartSession(ctx, c.DeviceID, c.Port)
+	return err
 }
 
 func main() {
@@ -253,17 +275,26 @@ func (r *RiceLa) setCounter(key string, v float64) {
 type RiceLa struct {
 	client      *tesla.Client
 	chargepoint *chargepoint.Client
+	chargers    *chargerRegistry
+	discovery   *stationDiscovery
+	policy      policy.Policy
+	idle        *idleTracker
+	notifier    *notify.Dispatcher
 
 	mu struct {
 		sync.Mutex
 
-		charging bool
+		charging    bool
+		sessionCost float64
 
 		gauges map[string]prometheus.Gauge
 	}
 }
 
 func pollTime(data VehicleData) time.Duration {
+	if data.ChargeState.ChargingState == StateCharging {
+		return *chargingPollTime
+	}
 	if !data.VehicleState.Locked && (data.DriveState.ShiftState == nil || data.DriveState.ShiftState == "P" || data.DriveState.ShiftState == "R") && !data.ChargeState.ChargePortDoorOpen {
 		return *activePollTime
 	}
@@ -273,29 +304,82 @@ func pollTime(data VehicleData) time.Duration {
 	return *standbyPollTime
 }
 
+// nearbyCharger returns the closest configured charger or, if there isn't
+// one, a discovered ChargePoint station. It returns nil if neither is nearby.
+func (r *RiceLa) nearbyCharger(ctx context.Context, data tesla.DriveState) (Charger, error) {
+	latlng := s2.LatLngFromDegrees(data.Latitude, data.Longitude)
+	if chargers := r.chargers.Nearby(latlng); len(chargers) > 0 {
+		return chargers[0], nil
+	}
+	charger, err := r.discoverCharger(ctx, latlng)
+	if err != nil {
+		r.notify(ctx, notify.ChargingFailed, "Charging failed", "Failed to search for ChargePoint stations: %v", err)
+		return nil, err
+	}
+	return charger, nil
+}
+
 func (r *RiceLa) startNearbyCharging(ctx context.Context, data tesla.DriveState) error {
 	log.Println("starting charging")
-	latlng := s2.LatLngFromDegrees(data.Latitude, data.Longitude)
-	for _, charger := range knownChargers {
-		if charger.DistanceInMeters(latlng) < 20 {
-			return charger.Start(ctx, r)
-		}
+	charger, err := r.nearbyCharger(ctx, data)
+	if err != nil {
+		return err
+	}
+	if charger == nil {
+		r.notify(ctx, notify.ChargingFailed, "No charger nearby", "The charge port opened at %f,%f but there's no charger nearby.", data.Latitude, data.Longitude)
+		return nil
+	}
+	if err := charger.Start(ctx, r); err != nil {
+		r.notify(ctx, notify.ChargingFailed, "Charging failed", "Failed to start %s: %v", charger, err)
+		return err
 	}
+	r.notify(ctx, notify.ChargingStarted, "Charging started", "Started charging at %s.", charger)
 	return nil
 }
 
-func (r *RiceLa) stopCharging(ctx context.Context) error {
-	log.Println("stop charging")
+// stopSessions stops the active ChargePoint sessions and returns how many
+// were stopped.
+func (r *RiceLa) stopSessions(ctx context.Context, reason string) (int, error) {
+	log.Printf("stop charging: %s", reason)
 	userStatus, err := r.chargepoint.UserStatus(ctx)
 	log.Printf("Charge Point user status %+v", userStatus)
 	if err != nil {
-		return err
+		r.notify(ctx, notify.ChargePointError, "ChargePoint error", "Failed to get the session to stop: %v", err)
+		return 0, err
 	}
+	now := time.Now()
+	stopped := 0
 	for _, station := range userStatus.Charging.Stations {
 		if err := r.chargepoint.StopSession(ctx, userStatus.Charging.SessionID, station.DeviceID); err != nil {
-			return err
+			r.notify(ctx, notify.ChargePointError, "ChargePoint error", "Failed to stop charging at %s: %v", station.Name, err)
+			return stopped, err
 		}
+		stopped++
+		// Only track sessions once they've stopped so a failed stop isn't
+		// reported as idle while it's still charging.
+		r.idle.Track(userStatus.Charging.SessionID, station.DeviceID, now, r.chargers.IdleGrace(station.DeviceID))
+		r.notify(ctx, notify.ChargingStopped, "Charging stopped", "Stopped charging at %s: %s.", station.Name, reason)
 	}
+	return stopped, nil
+}
+
+// stopCharging stops the car charging. ChargePoint sessions are stopped if
+// there are any, otherwise the car is told to stop through the Tesla API,
+// such as at home or at other networks' chargers. It fails if neither
+// stopped charging.
+func (r *RiceLa) stopCharging(ctx context.Context, v vehicleCharger, reason string) error {
+	stopped, err := r.stopSessions(ctx, reason)
+	if stopped > 0 {
+		return err
+	}
+	if verr := v.StopCharging(); verr != nil {
+		r.notify(ctx, notify.ChargingFailed, "Failed to stop charging", "Failed to stop charging (%s): %v", reason, verr)
+		if err != nil {
+			return errors.Wrapf(verr, "stopping charging through the Tesla API after ChargePoint failed: %v", err)
+		}
+		return errors.Wrap(verr, "stopping charging through the Tesla API")
+	}
+	r.notify(ctx, notify.ChargingStopped, "Charging stopped", "Stopped charging: %s.", reason)
 	return nil
 }
 
@@ -315,6 +399,8 @@ func (r *RiceLa) charging() bool {
 
 func (r *RiceLa) monitorVehicle(ctx context.Context, v *tesla.Vehicle) error {
 	var data, prevData *VehicleData
+	var unlocked unlockedMonitor
+	var wait chargeWait
 	for {
 		b := backoff.NewExponentialBackOff()
 		b.MaxElapsedTime = 1 * time.Minute
@@ -331,17 +417,28 @@ func (r *RiceLa) monitorVehicle(ctx context.Context, v *tesla.Vehicle) error {
 
 		pilotCurrent, _ := data.ChargeState.ChargerPilotCurrent.(float64)
 		if data.ChargeState.ChargingState == StateComplete && pilotCurrent > 1 {
-			if err := r.stopCharging(ctx); err != nil {
+			if _, err := r.stopSessions(ctx, "charging complete"); err != nil {
 				return err
 			}
 		}
 
-		if prevData != nil && !prevData.ChargeState.ChargePortDoorOpen && data.ChargeState.ChargePortDoorOpen {
-			if err := r.startNearbyCharging(ctx, data.DriveState); err != nil {
-				return err
+		if data.ChargeState.ChargingState == StateCharging {
+			if d := r.evaluatePolicy(r.policyState(*data)); d.Stop {
+				if err := r.stopCharging(ctx, v, d.Rule+": "+d.Reason); err != nil {
+					return err
+				}
+				wait.hold(d)
 			}
 		}
 
+		if err := r.startCharging(ctx, v, &wait, prevData, data, time.Now()); err != nil {
+			return err
+		}
+
+		r.checkIdle(ctx, *data)
+		if unlocked.update(*data, time.Now(), *unlockedNotify) {
+			r.notify(ctx, notify.CarUnlocked, "Car unlocked", "%s has been parked and unlocked for %s.", v.DisplayName, *unlockedNotify)
+		}
 		r.setCharging(data.ChargeState.ChargingState == StateCharging)
 
 		prevData = data
@@ -384,11 +481,38 @@ func (r *RiceLa) pollCarServer() error {
 
 func (r *RiceLa) run() error {
 	r.mu.gauges = map[string]prometheus.Gauge{}
+	r.idle = newIdleTracker()
 
 	eg, ctx := errgroup.WithContext(context.Background())
 
+	var err error
+	r.chargers, err = loadChargerRegistry(*chargersFile)
+	if err != nil {
+		return err
+	}
+	r.chargers.token = os.Getenv("RICELA_API_TOKEN")
+	eg.Go(func() error {
+		return r.chargers.Watch(ctx, *chargersPollTime)
+	})
+	r.discovery, err = newStationDiscovery(*discoverAllow, *discoverRadius, *discoverLevel, *discoverFree)
+	if err != nil {
+		return err
+	}
+	r.policy, err = newPolicy()
+	if err != nil {
+		return err
+	}
+	if *notifyConfig != "" {
+		r.notifier, err = notify.LoadConfig(*notifyConfig)
+		if err != nil {
+			return err
+		}
+	}
+
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/chargers", r.chargers)
+	mux.Handle("/chargers/", r.chargers)
 
 	tokenJSON := os.Getenv("TESLA_TOKEN_JSON")
 	var token Token
@@ -396,7 +520,6 @@ func (r *RiceLa) run() error {
 		return err
 	}
 
-	var err error
 	r.client, err = tesla.NewClientWithToken(
 		&tesla.Auth{
 			ClientID:     os.Getenv("TESLA_CLIENT_ID"),
@@ -443,6 +566,7 @@ func (r *RiceLa) run() error {
 			sessions, err := r.chargepoint.GetSessions(ctx)
 			if err != nil {
 				log.Println("chargpoint stats error", err)
+				r.notify(ctx, notify.ChargePointError, "ChargePoint error", "Failed to get sessions: %v", err)
 			}
 			if len(sessions) > 0 {
 				lastSession := sessions[len(sessions)-1]
@@ -453,8 +577,14 @@ func (r *RiceLa) run() error {
 				r.setCounter("chargepoint:latest:latitude", lastSession.Lat)
 				r.setCounter("chargepoint:latest:longitude", lastSession.Lon)
 
+				if lastSession.CurrentCharging != chargepoint.ChargingDone {
+					r.setSessionCost(lastSession.TotalAmount)
+				} else {
+					r.setSessionCost(0)
+				}
+
 				if lastSession.CurrentCharging == chargepoint.ChargingFullyCharged {
-					if err := r.stopCharging(ctx); err != nil {
+					if _, err := r.stopSessions(ctx, "fully charged"); err != nil {
 						log.Printf("failed to stop charging: %+v", err)
 					}
 				}
@@ -471,10 +601,16 @@ func (r *RiceLa) run() error {
 			r.setCounter("chargepoint:miles_added", milesAdded)
 			r.setCounter("chargepoint:energy_kwh", energyKwh)
 
+			// Poll faster while charging to catch fully charged sessions
+			// before idle fees start.
+			wait := *chargePointPollTime
+			if r.charging() {
+				wait = *chargingPollTime
+			}
 			select {
 			case <-ctx.Done():
 				return nil
-			case <-time.NewTimer(*chargePointPollTime).C:
+			case <-time.NewTimer(wait).C:
 			}
 		}
 	})
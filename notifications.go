@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/d4l3k/ricela/notify"
+)
+
+// notify sends an event in the background so slow notifiers don't hold up
+// polling.
+func (r *RiceLa) notify(ctx context.Context, event, title, format string, args ...interface{}) {
+	e := notify.Event{
+		Type:    event,
+		Time:    time.Now(),
+		Title:   title,
+		Message: fmt.Sprintf(format, args...),
+	}
+	go func() {
+		if err := r.notifier.Notify(ctx, e); err != nil {
+			log.Printf("failed to notify: %+v", err)
+		}
+	}()
+}
+
+// unlockedMonitor tracks how long the car has been parked and unlocked.
+type unlockedMonitor struct {
+	since    time.Time
+	notified bool
+}
+
+// update reports whether the car has been parked and unlocked for longer
+// than after. It only reports it once until the car is locked or driven.
+func (m *unlockedMonitor) update(data VehicleData, now time.Time, after time.Duration) bool {
+	parked := data.DriveState.ShiftState == nil || data.DriveState.ShiftState == "P"
+	if data.VehicleState.Locked || !parked {
+		*m = unlockedMonitor{}
+		return false
+	}
+	if m.since.IsZero() {
+		m.since = now
+	}
+	if m.notified || now.Sub(m.since) < after {
+		return false
+	}
+	m.notified = true
+	return true
+}
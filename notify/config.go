@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultRateLimitMinutes is used if the config doesn't set a rate limit.
+const DefaultRateLimitMinutes = 5
+
+// Notifier types.
+const (
+	TypeWebhook = "webhook"
+	TypePush    = "push"
+	TypeSMTP    = "smtp"
+)
+
+// Config is the notification config file.
+type Config struct {
+	// RateLimitMinutes is the minimum time between events of the same type
+	// sent to a notifier. It defaults to DefaultRateLimitMinutes if it's left
+	// out.
+	RateLimitMinutes float64          `json:"rate_limit_minutes"`
+	Notifiers        []NotifierConfig `json:"notifiers"`
+}
+
+func (c *Config) UnmarshalJSON(b []byte) error {
+	type config Config
+	out := config{RateLimitMinutes: DefaultRateLimitMinutes}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return err
+	}
+	*c = Config(out)
+	return nil
+}
+
+// NotifierConfig configures a notifier and the events it's subscribed to.
+type NotifierConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Events are the event types to send, all of them if it's empty.
+	Events []string `json:"events,omitempty"`
+
+	// URL is used by webhook and push notifiers.
+	URL string `json:"url,omitempty"`
+	// Token is used by push notifiers.
+	Token string `json:"token,omitempty"`
+
+	// These are used by smtp notifiers.
+	Addr     string   `json:"addr,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+}
+
+// Notifier returns the Notifier for the type.
+func (c NotifierConfig) Notifier() (Notifier, error) {
+	switch c.Type {
+	case TypeWebhook:
+		if c.URL == "" {
+			return nil, errors.Errorf("%s: missing url", c.Name)
+		}
+		return Webhook{URL: c.URL}, nil
+	case TypePush:
+		if c.URL == "" {
+			return nil, errors.Errorf("%s: missing url", c.Name)
+		}
+		return Push{URL: c.URL, Token: c.Token}, nil
+	case TypeSMTP:
+		if c.Addr == "" || c.From == "" || len(c.To) == 0 {
+			return nil, errors.Errorf("%s: smtp needs addr, from and to", c.Name)
+		}
+		return SMTP{
+			Addr:     c.Addr,
+			Username: c.Username,
+			Password: c.Password,
+			From:     c.From,
+			To:       c.To,
+		}, nil
+	default:
+		return nil, errors.Errorf("%s: unknown notifier type %q", c.Name, c.Type)
+	}
+}
+
+// Dispatcher returns a Dispatcher sending to the configured notifiers.
+func (c Config) Dispatcher() (*Dispatcher, error) {
+	if c.RateLimitMinutes < 0 {
+		return nil, errors.Errorf("invalid rate limit %f", c.RateLimitMinutes)
+	}
+	d := NewDispatcher(time.Duration(c.RateLimitMinutes * float64(time.Minute)))
+	for _, nc := range c.Notifiers {
+		n, err := nc.Notifier()
+		if err != nil {
+			return nil, err
+		}
+		if err := d.Subscribe(nc.Name, n, nc.Events...); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// LoadConfig reads the config file and returns its Dispatcher.
+func LoadConfig(path string) (*Dispatcher, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(body, &c); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", path)
+	}
+	d, err := c.Dispatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+	return d, nil
+}
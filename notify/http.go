@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const httpTimeout = 30 * time.Second
+
+var httpClient = &http.Client{Timeout: httpTimeout}
+
+// Webhook posts events as JSON to a URL.
+type Webhook struct {
+	URL string
+}
+
+func (w Webhook) Notify(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return post(ctx, w.URL, "application/json", bytes.NewReader(body), nil)
+}
+
+// Push sends events to an ntfy style push service: the message is posted as
+// the body with the title and type as headers.
+type Push struct {
+	URL string
+	// Token is sent as a bearer token if set.
+	Token string
+}
+
+func (p Push) Notify(ctx context.Context, e Event) error {
+	header := http.Header{}
+	header.Set("Title", e.Title)
+	header.Set("Tags", e.Type)
+	if p.Token != "" {
+		header.Set("Authorization", "Bearer "+p.Token)
+	}
+	return post(ctx, p.URL, "text/plain; charset=utf-8", strings.NewReader(e.Message), header)
+}
+
+func post(ctx context.Context, url, contentType string, body io.Reader, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+// Package notify sends notifications about charging and the car to webhooks,
+// email and push services.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Event types.
+const (
+	ChargingStarted  = "charging_started"
+	ChargingStopped  = "charging_stopped"
+	ChargingFailed   = "charging_failed"
+	CarUnlocked      = "car_unlocked"
+	ChargePointError = "chargepoint_error"
+	IdleFee          = "idle_fee"
+)
+
+// Events is every event type, for validating subscriptions.
+var Events = []string{
+	ChargingStarted,
+	ChargingStopped,
+	ChargingFailed,
+	CarUnlocked,
+	ChargePointError,
+	IdleFee,
+}
+
+// Event is something that happened.
+type Event struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+}
+
+// Notifier delivers events.
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+type subscription struct {
+	name     string
+	notifier Notifier
+	// events is the set of event types to send, nil for all of them.
+	events map[string]bool
+}
+
+type rateKey struct {
+	subscription int
+	event        string
+}
+
+// Dispatcher sends events to the notifiers subscribed to them. A nil
+// Dispatcher drops all events.
+type Dispatcher struct {
+	// RateLimit is the minimum time between events of the same type sent to
+	// a notifier, extra events are dropped.
+	RateLimit time.Duration
+
+	subscriptions []subscription
+	now           func() time.Time
+
+	mu   sync.Mutex
+	sent map[rateKey]time.Time
+}
+
+func NewDispatcher(rateLimit time.Duration) *Dispatcher {
+	return &Dispatcher{
+		RateLimit: rateLimit,
+		now:       time.Now,
+		sent:      map[rateKey]time.Time{},
+	}
+}
+
+// Subscribe sends events of the given types to n, or all events if there are
+// none.
+func (d *Dispatcher) Subscribe(name string, n Notifier, events ...string) error {
+	s := subscription{name: name, notifier: n}
+	for _, e := range events {
+		if !validEvent(e) {
+			return errors.Errorf("%s: unknown event %q", name, e)
+		}
+		if s.events == nil {
+			s.events = map[string]bool{}
+		}
+		s.events[e] = true
+	}
+	d.subscriptions = append(d.subscriptions, s)
+	return nil
+}
+
+func validEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// limited reports whether an event of this type was sent to subscription i
+// within the rate limit.
+func (d *Dispatcher) limited(i int, event string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	last, ok := d.sent[rateKey{i, event}]
+	return ok && now.Sub(last) < d.RateLimit
+}
+
+// markSent records that an event was sent to subscription i. Failed sends
+// aren't recorded so they can be retried.
+func (d *Dispatcher) markSent(i int, event string, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sent[rateKey{i, event}] = now
+}
+
+// Notify sends e to the subscribed notifiers. It returns the errors from all
+// the notifiers that failed.
+func (d *Dispatcher) Notify(ctx context.Context, e Event) error {
+	if d == nil {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = d.now()
+	}
+	var failed []string
+	for i, s := range d.subscriptions {
+		if s.events != nil && !s.events[e.Type] {
+			continue
+		}
+		if d.limited(i, e.Type, e.Time) {
+			continue
+		}
+		if err := s.notifier.Notify(ctx, e); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", s.name, err))
+			continue
+		}
+		d.markSent(i, e.Type, e.Time)
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("sending %s: %s", e.Type, strings.Join(failed, "; "))
+	}
+	return nil
+}
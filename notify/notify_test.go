@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	events []string
+	// fail is returned instead of recording the event if it's set.
+	fail error
+}
+
+func (r *recorder) Notify(ctx context.Context, e Event) error {
+	if r.fail != nil {
+		return r.fail
+	}
+	r.events = append(r.events, e.Type)
+	return nil
+}
+
+func TestDispatcher(t *testing.T) {
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := NewDispatcher(5 * time.Minute)
+	d.now = func() time.Time { return now }
+
+	var all, charging recorder
+	if err := d.Subscribe("all", &all); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Subscribe("charging", &charging, ChargingStarted, ChargingStopped); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Subscribe("bad", &all, "nope"); err == nil {
+		t.Fatal("expected error for unknown event")
+	}
+
+	ctx := context.Background()
+	send := func(event string) {
+		if err := d.Notify(ctx, Event{Type: event}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	send(ChargingStarted)
+	send(CarUnlocked)
+	// Rate limited.
+	send(ChargingStarted)
+	now = now.Add(5 * time.Minute)
+	send(ChargingStarted)
+	send(ChargingStopped)
+
+	if want := []string{ChargingStarted, CarUnlocked, ChargingStarted, ChargingStopped}; !reflect.DeepEqual(all.events, want) {
+		t.Errorf("all got %v; expected %v", all.events, want)
+	}
+	if want := []string{ChargingStarted, ChargingStarted, ChargingStopped}; !reflect.DeepEqual(charging.events, want) {
+		t.Errorf("charging got %v; expected %v", charging.events, want)
+	}
+
+	// Failed sends don't count towards the rate limit.
+	flaky := &recorder{fail: errors.New("unavailable")}
+	d = NewDispatcher(5 * time.Minute)
+	d.now = func() time.Time { return now }
+	if err := d.Subscribe("flaky", flaky); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notify(ctx, Event{Type: IdleFee}); err == nil {
+		t.Error("expected error from failing notifier")
+	}
+	flaky.fail = nil
+	send(IdleFee)
+	if want := []string{IdleFee}; !reflect.DeepEqual(flaky.events, want) {
+		t.Errorf("flaky got %v after failing; expected %v", flaky.events, want)
+	}
+
+	var nilDispatcher *Dispatcher
+	if err := nilDispatcher.Notify(ctx, Event{Type: ChargingStarted}); err != nil {
+		t.Errorf("nil Dispatcher: %v", err)
+	}
+}
+
+func TestHTTPNotifiers(t *testing.T) {
+	type request struct {
+		header http.Header
+		body   string
+	}
+	requests := make(chan request, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- request{r.Header, string(body)}
+		if r.URL.Path == "/fail" {
+			http.Error(w, "nope", http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	e := Event{
+		Type:    ChargingStarted,
+		Time:    time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+		Title:   "Charging started",
+		Message: "at home",
+	}
+
+	if err := (Webhook{URL: ts.URL}).Notify(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+	req := <-requests
+	var got Event
+	if err := json.Unmarshal([]byte(req.body), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != e {
+		t.Errorf("webhook got %+v; expected %+v", got, e)
+	}
+
+	if err := (Push{URL: ts.URL, Token: "secret"}).Notify(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+	req = <-requests
+	if req.body != e.Message ||
+		req.header.Get("Title") != e.Title ||
+		req.header.Get("Tags") != e.Type ||
+		req.header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("push got %q %+v", req.body, req.header)
+	}
+
+	if err := (Webhook{URL: ts.URL + "/fail"}).Notify(ctx, e); err == nil {
+		t.Error("expected error from failing webhook")
+	}
+	<-requests
+}
+
+func TestConfig(t *testing.T) {
+	var c Config
+	if err := json.Unmarshal([]byte(`{
+		"notifiers": [
+			{"name": "hook", "type": "webhook", "url": "http://localhost/hook"},
+			{"name": "phone", "type": "push", "url": "http://localhost/topic", "events": ["idle_fee"]},
+			{"name": "email", "type": "smtp", "addr": "localhost:25", "from": "a@example.com", "to": ["b@example.com"]}
+		]
+	}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.RateLimitMinutes != DefaultRateLimitMinutes {
+		t.Errorf("RateLimitMinutes = %f; expected default", c.RateLimitMinutes)
+	}
+	d, err := c.Dispatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.subscriptions) != 3 || d.RateLimit != DefaultRateLimitMinutes*time.Minute {
+		t.Errorf("unexpected dispatcher %+v", d)
+	}
+
+	for _, nc := range []NotifierConfig{
+		{Name: "a", Type: "carrier pigeon"},
+		{Name: "b", Type: TypeWebhook},
+		{Name: "c", Type: TypeSMTP, Addr: "localhost:25"},
+	} {
+		if _, err := nc.Notifier(); err == nil {
+			t.Errorf("%s: expected error", nc.Name)
+		}
+	}
+}
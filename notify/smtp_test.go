@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpStub accepts a single message and sends what it received on messages.
+func smtpStub(l net.Listener, messages chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) {
+		fmt.Fprintf(conn, "%s\r\n", s)
+	}
+
+	var received strings.Builder
+	reply("220 localhost stub")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"), strings.HasPrefix(cmd, "RCPT TO:"):
+			received.WriteString(strings.TrimSpace(line) + "\n")
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				received.WriteString(line)
+			}
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			messages <- received.String()
+			return
+		default:
+			reply("500 unknown command")
+		}
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	messages := make(chan string, 1)
+	go smtpStub(l, messages)
+
+	s := SMTP{
+		Addr: l.Addr().String(),
+		From: "ricela@example.com",
+		To:   []string{"me@example.com"},
+	}
+	e := Event{
+		Type:    IdleFee,
+		Time:    time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+		Title:   "Idle fees",
+		Message: "still plugged in\nafter 10m",
+	}
+	if err := s.Notify(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := <-messages
+	for _, want := range []string{
+		"MAIL FROM:<ricela@example.com>",
+		"RCPT TO:<me@example.com>",
+		"Subject: Idle fees\r\n",
+		"To: me@example.com\r\n",
+		"\r\n\r\nstill plugged in\r\nafter 10m\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestSMTPTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	// Accept connections but never reply.
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	s := SMTP{Addr: l.Addr().String(), From: "a@example.com", To: []string{"b@example.com"}}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Notify(ctx, Event{Type: IdleFee})
+	}()
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Error("expected an error from a server that never replies")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify didn't time out")
+	}
+}
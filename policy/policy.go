@@ -0,0 +1,162 @@
+// Package policy decides whether the car should keep charging.
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// State is what the policy knows about the car and the charging session when
+// it's evaluated.
+type State struct {
+	Time time.Time
+	// BatteryLevel is the state of charge in percent.
+	BatteryLevel float64
+	// EnergyKWh is the energy added during the current session.
+	EnergyKWh float64
+	// Cost is the amount billed so far for the current session.
+	Cost float64
+}
+
+// Decision is the outcome of evaluating a Policy.
+type Decision struct {
+	// Rule is the rule that stopped charging or empty if charging may
+	// continue.
+	Rule   string
+	Stop   bool
+	Reason string
+}
+
+func (d Decision) String() string {
+	if !d.Stop {
+		return "continue charging"
+	}
+	return fmt.Sprintf("stop charging: %s: %s", d.Rule, d.Reason)
+}
+
+// Rule names used in decisions.
+const (
+	RuleTargetSOC = "target_soc"
+	RuleMaxCost   = "max_cost"
+	RuleMaxEnergy = "max_energy"
+	RuleHours     = "hours"
+)
+
+// Policy is a set of rules that stop charging. The zero value never stops
+// charging.
+type Policy struct {
+	// TargetSOC stops charging at this state of charge in percent,
+	// regardless of the car's charge limit. 0 disables it.
+	TargetSOC float64
+	// MaxCost stops charging once a session costs this much. 0 disables it.
+	MaxCost float64
+	// MaxEnergyKWh stops charging once a session has added this much
+	// energy. 0 disables it.
+	MaxEnergyKWh float64
+	// Hours are the times charging is allowed. Empty allows any time.
+	Hours []Window
+}
+
+// Evaluate checks the rules in order and returns the first one that stops
+// charging.
+func (p Policy) Evaluate(s State) Decision {
+	if p.TargetSOC > 0 && s.BatteryLevel >= p.TargetSOC {
+		return Decision{
+			Rule:   RuleTargetSOC,
+			Stop:   true,
+			Reason: fmt.Sprintf("battery at %.0f%%, target %.0f%%", s.BatteryLevel, p.TargetSOC),
+		}
+	}
+	if p.MaxCost > 0 && s.Cost >= p.MaxCost {
+		return Decision{
+			Rule:   RuleMaxCost,
+			Stop:   true,
+			Reason: fmt.Sprintf("session cost %.2f, max %.2f", s.Cost, p.MaxCost),
+		}
+	}
+	if p.MaxEnergyKWh > 0 && s.EnergyKWh >= p.MaxEnergyKWh {
+		return Decision{
+			Rule:   RuleMaxEnergy,
+			Stop:   true,
+			Reason: fmt.Sprintf("added %.1f kWh, max %.1f kWh", s.EnergyKWh, p.MaxEnergyKWh),
+		}
+	}
+	if len(p.Hours) > 0 && !inWindows(p.Hours, s.Time) {
+		return Decision{
+			Rule:   RuleHours,
+			Stop:   true,
+			Reason: fmt.Sprintf("%s is outside of %s", s.Time.Format("15:04"), FormatWindows(p.Hours)),
+		}
+	}
+	return Decision{}
+}
+
+// Window is a range of hours of the day, [Start, End). Windows where End is
+// before Start wrap around midnight.
+type Window struct {
+	Start int
+	End   int
+}
+
+// Contains reports whether t's local hour is within the window.
+func (w Window) Contains(t time.Time) bool {
+	h := t.Hour()
+	if w.Start <= w.End {
+		return h >= w.Start && h < w.End
+	}
+	return h >= w.Start || h < w.End
+}
+
+func (w Window) String() string {
+	return fmt.Sprintf("%d-%d", w.Start, w.End)
+}
+
+func inWindows(windows []Window, t time.Time) bool {
+	for _, w := range windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseWindows parses comma separated hour ranges such as "22-7,12-14".
+func ParseWindows(s string) ([]Window, error) {
+	var windows []Window
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bounds := strings.Split(part, "-")
+		if len(bounds) != 2 {
+			return nil, errors.Errorf("invalid hour range %q", part)
+		}
+		var w Window
+		for i, dst := range []*int{&w.Start, &w.End} {
+			hour, err := strconv.Atoi(strings.TrimSpace(bounds[i]))
+			if err != nil || hour < 0 || hour > 24 {
+				return nil, errors.Errorf("invalid hour %q in %q", bounds[i], part)
+			}
+			*dst = hour
+		}
+		if w.Start == w.End {
+			return nil, errors.Errorf("empty hour range %q", part)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// FormatWindows is the inverse of ParseWindows.
+func FormatWindows(windows []Window) string {
+	parts := make([]string, len(windows))
+	for i, w := range windows {
+		parts[i] = w.String()
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,66 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2021, 1, 1, hour, 30, 0, 0, time.UTC)
+	}
+	p := Policy{
+		TargetSOC:    80,
+		MaxCost:      10,
+		MaxEnergyKWh: 40,
+		Hours:        []Window{{22, 7}},
+	}
+
+	cases := []struct {
+		state State
+		rule  string
+	}{
+		{State{Time: at(23), BatteryLevel: 50, EnergyKWh: 10, Cost: 2}, ""},
+		{State{Time: at(3), BatteryLevel: 80}, RuleTargetSOC},
+		{State{Time: at(3), Cost: 10.5}, RuleMaxCost},
+		{State{Time: at(3), EnergyKWh: 40}, RuleMaxEnergy},
+		{State{Time: at(12)}, RuleHours},
+		{State{Time: at(7)}, RuleHours},
+		// The first matching rule wins.
+		{State{Time: at(12), BatteryLevel: 90, Cost: 20}, RuleTargetSOC},
+	}
+	for i, c := range cases {
+		d := p.Evaluate(c.state)
+		if d.Rule != c.rule || d.Stop != (c.rule != "") {
+			t.Errorf("%d. Evaluate(%+v) = %+v; expected rule %q", i, c.state, d, c.rule)
+		}
+	}
+
+	if d := (Policy{}).Evaluate(State{Time: at(12), BatteryLevel: 100, Cost: 100, EnergyKWh: 100}); d.Stop {
+		t.Errorf("zero Policy stopped charging: %+v", d)
+	}
+}
+
+func TestParseWindows(t *testing.T) {
+	windows, err := ParseWindows("22-7, 12-14")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Window{{22, 7}, {12, 14}}
+	if !reflect.DeepEqual(windows, want) {
+		t.Fatalf("ParseWindows = %+v; expected %+v", windows, want)
+	}
+	if out := FormatWindows(windows); out != "22-7,12-14" {
+		t.Errorf("FormatWindows = %q", out)
+	}
+
+	if windows, err := ParseWindows(""); err != nil || windows != nil {
+		t.Errorf("ParseWindows(\"\") = %+v, %v", windows, err)
+	}
+	for _, s := range []string{"22", "a-7", "1-25", "3-3", "1-2-3"} {
+		if _, err := ParseWindows(s); err == nil {
+			t.Errorf("ParseWindows(%q) expected error", s)
+		}
+	}
+}
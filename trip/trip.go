@@ -0,0 +1,228 @@
+// Package trip detects drives from decoded CAN signals and summarizes them.
+package trip
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+)
+
+// Trip is the summary of a single drive.
+type Trip struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	DistanceMiles    float64 `json:"distance_miles"`
+	EnergyUsedKWh    float64 `json:"energy_used_kwh"`
+	RegenKWh         float64 `json:"regen_kwh"`
+	MaxSpeedMPH      float64 `json:"max_speed_mph"`
+	MaxPowerKW       float64 `json:"max_power_kw"`
+	ElevationChangeM float64 `json:"elevation_change_m"`
+}
+
+// Duration is how long the trip took.
+func (t Trip) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// WhPerMile is the average efficiency over the trip.
+func (t Trip) WhPerMile() float64 {
+	if t.DistanceMiles <= 0 {
+		return 0
+	}
+	return t.EnergyUsedKWh * 1000 / t.DistanceMiles
+}
+
+const (
+	// DefaultIdleTimeout is how long the car can sit stationary out of
+	// park before the trip is considered over.
+	DefaultIdleTimeout = 10 * time.Minute
+
+	// stoppedSpeed is the speed in mph below which the car is stationary.
+	stoppedSpeed = 0.5
+)
+
+// Detector turns a stream of decoded signal values into trips. A trip starts
+// when the car is put in drive or reverse, or starts moving, and ends when
+// it's parked or has been stationary for IdleTimeout.
+type Detector struct {
+	IdleTimeout time.Duration
+
+	// latest holds the most recent value of each signal we track so the
+	// counters have a starting point when a trip begins.
+	latest map[string]float64
+	// start holds the counter values at the start of the current trip.
+	start map[string]float64
+
+	current   *Trip
+	lastMoved time.Time
+}
+
+func NewDetector() *Detector {
+	return &Detector{
+		IdleTimeout: DefaultIdleTimeout,
+		latest:      map[string]float64{},
+	}
+}
+
+// counters are the monotonic signals that are summarized as deltas.
+var counters = []string{
+	can.OdometerMilesKey,
+	can.TotalDischargeKey,
+	can.TotalChargeKey,
+	can.ElevationKey,
+}
+
+// InTrip reports whether a trip is in progress.
+func (d *Detector) InTrip() bool {
+	return d.current != nil
+}
+
+// Update processes the values decoded from a frame received at t. It returns
+// the summary of a trip if that trip ended.
+func (d *Detector) Update(t time.Time, values []can.Value) (Trip, bool) {
+	var gear, speed float64
+	var haveGear, haveSpeed bool
+	for _, v := range values {
+		switch v.Signal.Name {
+		case can.GearKey:
+			gear = v.Value
+			haveGear = true
+		case can.SignedSpeedKey:
+			speed = math.Abs(v.Value)
+			haveSpeed = true
+		case can.OdometerMilesKey, can.TotalDischargeKey, can.TotalChargeKey,
+			can.ElevationKey, can.FrontPowerKey, can.RearPowerKey:
+			d.latest[v.Signal.Name] = v.Value
+			if _, ok := d.start[v.Signal.Name]; d.current != nil && !ok {
+				d.start[v.Signal.Name] = v.Value
+			}
+		}
+	}
+
+	moving := haveSpeed && speed >= stoppedSpeed
+	if d.current == nil {
+		if (haveGear && (gear == can.GearDrive || gear == can.GearReverse)) || moving {
+			d.begin(t)
+		} else {
+			return Trip{}, false
+		}
+	}
+
+	if moving {
+		d.lastMoved = t
+	}
+	if haveSpeed && speed > d.current.MaxSpeedMPH {
+		d.current.MaxSpeedMPH = speed
+	}
+	power := d.latest[can.FrontPowerKey] + d.latest[can.RearPowerKey]
+	if power > d.current.MaxPowerKW {
+		d.current.MaxPowerKW = power
+	}
+	d.current.End = t
+
+	parked := haveGear && gear == can.GearPark
+	idle := d.IdleTimeout > 0 && t.Sub(d.lastMoved) > d.IdleTimeout
+	if parked || idle {
+		return d.end()
+	}
+	return Trip{}, false
+}
+
+// Flush ends the current trip, if any, for example when the frame source
+// disconnects.
+func (d *Detector) Flush() (Trip, bool) {
+	if d.current == nil {
+		return Trip{}, false
+	}
+	return d.end()
+}
+
+func (d *Detector) begin(t time.Time) {
+	d.current = &Trip{Start: t, End: t}
+	d.lastMoved = t
+	d.start = map[string]float64{}
+	for _, key := range counters {
+		if v, ok := d.latest[key]; ok {
+			d.start[key] = v
+		}
+	}
+}
+
+// delta returns how much a counter changed over the trip. Counters first seen
+// after the trip started are measured from their first value during the trip.
+func (d *Detector) delta(key string) float64 {
+	last, ok := d.latest[key]
+	if !ok {
+		return 0
+	}
+	start, ok := d.start[key]
+	if !ok {
+		return 0
+	}
+	return last - start
+}
+
+func (d *Detector) end() (Trip, bool) {
+	trip := *d.current
+	d.current = nil
+
+	trip.DistanceMiles = d.delta(can.OdometerMilesKey)
+	trip.RegenKWh = d.delta(can.TotalChargeKey)
+	trip.EnergyUsedKWh = d.delta(can.TotalDischargeKey) - trip.RegenKWh
+	trip.ElevationChangeM = d.delta(can.ElevationKey)
+
+	// Ignore trips where the car never moved, such as shifting into drive
+	// and straight back to park.
+	if trip.DistanceMiles <= 0 && trip.MaxSpeedMPH == 0 {
+		return Trip{}, false
+	}
+	return trip, true
+}
+
+// Append writes the trip as a JSON line to the file at path.
+func Append(path string, trip Trip) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(trip); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads all trips from the file at path. A missing file has no trips.
+func Load(path string) ([]Trip, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read reads trips written by Append.
+func Read(r io.Reader) ([]Trip, error) {
+	var trips []Trip
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		var trip Trip
+		if err := json.Unmarshal(s.Bytes(), &trip); err != nil {
+			return nil, err
+		}
+		trips = append(trips, trip)
+	}
+	return trips, s.Err()
+}
@@ -0,0 +1,103 @@
+package trip
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/d4l3k/ricela/can"
+)
+
+func values(t *testing.T, kv map[string]float64) []can.Value {
+	var out []can.Value
+	for name, value := range kv {
+		signal := can.DefaultDBC.Signal(name)
+		if signal == nil {
+			t.Fatalf("unknown signal %q", name)
+		}
+		out = append(out, can.Value{Signal: signal, Value: value})
+	}
+	return out
+}
+
+func TestDetector(t *testing.T) {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := NewDetector()
+
+	steps := []map[string]float64{
+		{can.OdometerMilesKey: 1000, can.TotalDischargeKey: 500, can.TotalChargeKey: 400, can.ElevationKey: 100},
+		{can.GearKey: can.GearPark},
+		{can.GearKey: can.GearDrive},
+		{can.SignedSpeedKey: 30, can.FrontPowerKey: 50, can.RearPowerKey: 100},
+		{can.SignedSpeedKey: 65, can.FrontPowerKey: 20, can.RearPowerKey: 40},
+		{can.OdometerMilesKey: 1010, can.TotalDischargeKey: 503.5, can.TotalChargeKey: 400.5, can.ElevationKey: 80},
+		{can.SignedSpeedKey: 0},
+	}
+	for i, step := range steps {
+		if _, ok := d.Update(start.Add(time.Duration(i)*time.Minute), values(t, step)); ok {
+			t.Fatalf("step %d: unexpected end of trip", i)
+		}
+	}
+	if !d.InTrip() {
+		t.Fatal("expected trip to be in progress")
+	}
+
+	end := start.Add(time.Duration(len(steps)) * time.Minute)
+	trip, ok := d.Update(end, values(t, map[string]float64{can.GearKey: can.GearPark}))
+	if !ok {
+		t.Fatal("expected trip to end when parked")
+	}
+	want := Trip{
+		Start:            start.Add(2 * time.Minute),
+		End:              end,
+		DistanceMiles:    10,
+		EnergyUsedKWh:    3,
+		RegenKWh:         0.5,
+		MaxSpeedMPH:      65,
+		MaxPowerKW:       150,
+		ElevationChangeM: -20,
+	}
+	if !reflect.DeepEqual(trip, want) {
+		t.Errorf("trip = %+v; expected %+v", trip, want)
+	}
+	if got := trip.WhPerMile(); math.Abs(got-300) > 1e-9 {
+		t.Errorf("WhPerMile() = %f; expected 300", got)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"start":"2020-06-01T12:02:00Z","end":"2020-06-01T12:07:00Z","distance_miles":10,"energy_used_kwh":3,"regen_kwh":0.5,"max_speed_mph":65,"max_power_kw":150,"elevation_change_m":-20}` + "\n")
+	trips, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(trips, []Trip{want}) {
+		t.Errorf("Read() = %+v; expected %+v", trips, []Trip{want})
+	}
+}
+
+func TestDetectorIdle(t *testing.T) {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := NewDetector()
+
+	if _, ok := d.Update(start, values(t, map[string]float64{can.SignedSpeedKey: 10})); ok {
+		t.Fatal("unexpected end of trip")
+	}
+	if _, ok := d.Update(start.Add(time.Minute), values(t, map[string]float64{can.SignedSpeedKey: 0})); ok {
+		t.Fatal("unexpected end of trip")
+	}
+	trip, ok := d.Update(start.Add(time.Minute+DefaultIdleTimeout), values(t, map[string]float64{can.SignedSpeedKey: 0}))
+	if !ok {
+		t.Fatal("expected trip to end after idle timeout")
+	}
+	if trip.MaxSpeedMPH != 10 {
+		t.Errorf("MaxSpeedMPH = %f; expected 10", trip.MaxSpeedMPH)
+	}
+
+	// Shifting into drive and back without moving isn't a trip.
+	d.Update(start, values(t, map[string]float64{can.GearKey: can.GearDrive}))
+	if _, ok := d.Update(start, values(t, map[string]float64{can.GearKey: can.GearPark})); ok {
+		t.Error("expected no trip without movement")
+	}
+}